@@ -15,15 +15,15 @@
 package cmd
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"os"
-	"os/exec"
 	"strings"
 	"time"
 
 	"github.com/cove/oview/pkg/cubeplane"
+	"github.com/cove/oview/pkg/source"
 	"github.com/cove/oview/pkg/text2table"
 	"github.com/g3n/engine/util/application"
 
@@ -44,11 +44,32 @@ var (
 	file      string
 	command   string
 	usage     bool
+	format    = string(text2table.FormatAuto)
+	comment   string
+	key       []string
+	family    string
+	delimiter string
+	pattern   string
+	separator string
+	shell     = source.DefaultShell
+	dir       string
+	timeout   = 30
+	deadline  int
+	expire    = 60
+	follow    bool
+	window    = source.DefaultWindow
+	method    string
+	headers   []string
+	selector  string
+	tag       bool
+	labels    []string
+	sum       []string
+	span      = int(source.DefaultSpan / time.Second)
 )
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
-	Use:     "oview",
+	Use:     "oview [source]",
 	Version: "unknown",
 	Short:   "Displays a text table as a 3D rotating plane of cubes",
 	Long: `Takes a text table and displays it as a 3D rotating plane of cubes,
@@ -78,9 +99,32 @@ func init() {
 	rootCmd.PersistentFlags().IntVarP(&rotation, "rotations", "r", rotation, "How many seconds each rotation takes")
 	rootCmd.PersistentFlags().BoolVarP(&pause, "pause", "p", pause, "Start up with rotation paused to improve performance")
 	rootCmd.PersistentFlags().BoolVarP(&wireframe, "wireframe", "w", wireframe, "Render cubes as wireframes to improve performance")
-	rootCmd.PersistentFlags().StringVarP(&file, "file", "f", file, "Load data from file or use '-' to read from stdin")
+	rootCmd.PersistentFlags().StringVarP(&file, "file", "f", file, "Load data from file, whenever it changes, or use '-' to read from stdin")
+	rootCmd.PersistentFlags().BoolVar(&follow, "follow", follow, "Follow the lines appended to the file, like tail -F")
+	rootCmd.PersistentFlags().IntVar(&window, "window", window, "Show only this many of the last lines with --follow, -1 for all")
 	rootCmd.PersistentFlags().StringVarP(&command, "command", "c", command, "Command to run to get data from")
+	rootCmd.PersistentFlags().StringVar(&shell, "shell", shell, "Shell the command is run with, or '' to run it without one")
+	rootCmd.PersistentFlags().StringVar(&dir, "dir", dir, "Working directory of the command (default current)")
+	rootCmd.PersistentFlags().IntVarP(&timeout, "timeout", "t", timeout, "Seconds a command can go without output before it's killed, 0 for no limit")
+	rootCmd.PersistentFlags().IntVar(&deadline, "deadline", deadline, "Seconds a command can run before it's killed, even while printing, 0 for no limit")
+	rootCmd.PersistentFlags().IntVar(&expire, "expire", expire, "Seconds a sender to listen:, statsd: or influx: can go quiet before its rows are dropped, 0 for never")
+	rootCmd.PersistentFlags().BoolVar(&tag, "tag", tag, "Show the tables sent by each connection to listen: together, with a SOURCE column")
+	rootCmd.PersistentFlags().StringSliceVar(&labels, "label", labels, "Container labels docker: shows as columns, like com.docker.compose.service")
+	rootCmd.PersistentFlags().StringVar(&method, "method", method, "HTTP method the URL is requested with (default GET)")
+	rootCmd.PersistentFlags().StringArrayVarP(&headers, "header", "H", headers, "HTTP header like 'Authorization: Bearer x' to send with the request")
+	rootCmd.PersistentFlags().StringVar(&selector, "select", selector, "Path to the rows in JSON data like 'data.items[*]' (default found)")
 	rootCmd.PersistentFlags().BoolVarP(&usage, "usage", "u", true, "Show usage text in screen on startup")
+	rootCmd.PersistentFlags().StringVar(&format, "format", format, "Format of the data: auto, text, csv, tsv, json, prometheus, logfmt or box")
+	rootCmd.PersistentFlags().StringVar(&comment, "comment", comment, "Skip csv, tsv and logfmt lines starting with this character")
+	rootCmd.PersistentFlags().StringSliceVarP(&key, "key", "k", key, "Columns that identify a row, or the regexp groups logs: counts by, by name or position (default inferred)")
+	viper.BindPFlag("key", rootCmd.PersistentFlags().Lookup("key"))
+	rootCmd.PersistentFlags().StringVar(&family, "family", family, "Prometheus metric families to show, e.g. 'node_cpu_*' (default all)")
+	rootCmd.PersistentFlags().StringVarP(&pattern, "regexp", "e", pattern, "Regexp with named groups like (?P<pid>\\d+) picking the columns out of each line")
+	viper.BindPFlag("regexp", rootCmd.PersistentFlags().Lookup("regexp"))
+	rootCmd.PersistentFlags().StringSliceVar(&sum, "sum", sum, "Regexp groups whose values logs: sums, like the bytes sent")
+	rootCmd.PersistentFlags().IntVar(&span, "span", span, "Seconds of lines logs: counts")
+	rootCmd.PersistentFlags().StringVar(&separator, "separator", separator, "Field separator: comma, tab, semicolon, pipe, colon, space or a character (default detected)")
+	rootCmd.PersistentFlags().StringVar(&delimiter, "delimiter", delimiter, "Line separating the tables printed by a command that keeps running (default detected)")
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -112,12 +156,33 @@ func initConfig() {
 func view(cmd *cobra.Command, args []string) {
 
 	// validate command line args
-	if file == "" && command == "" {
-		fmt.Fprintln(os.Stderr, "Please specify either -f or -c to load data")
+	spec, err := sourceSpec(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		cmd.Usage()
 		os.Exit(-1)
 	}
 
+	f, err := text2table.ParseFormat(format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(-1)
+	}
+	sep, err := text2table.ParseSeparator(separator)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(-1)
+	}
+	header, err := source.ParseHeader(headers)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(-1)
+	}
+	opts := text2table.Options{Format: f, Key: keyColumns(), Family: family, Separator: sep, Regexp: viper.GetString("regexp"), Select: selector}
+	if comment != "" {
+		opts.Comment = []rune(comment)[0]
+	}
+
 	app, _ := application.Create(application.Options{
 		Title:  "oview",
 		Width:  900,
@@ -126,7 +191,6 @@ func view(cmd *cobra.Command, args []string) {
 
 	cp := cubeplane.Init(
 		app,
-		strings.Join(args, " "),
 		refresh,
 		wireframe,
 		size,
@@ -134,89 +198,90 @@ func view(cmd *cobra.Command, args []string) {
 		pause,
 		usage)
 
-	if command != "" {
-		go PollCmd(command, cp)
-	} else if file != "" {
-		go PollFile(file, cp)
+	src, err := source.New(spec, source.Config{
+		Options:   opts,
+		Interval:  time.Duration(refresh) * time.Second,
+		Timeout:   time.Duration(timeout) * time.Second,
+		Deadline:  time.Duration(deadline) * time.Second,
+		Expire:    time.Duration(expire) * time.Second,
+		Delimiter: delimiter,
+		Follow:    follow,
+		Window:    window,
+		Shell:     shell,
+		Dir:       dir,
+		Tag:       tag,
+		Sum:       sum,
+		Labels:    labels,
+		Span:      time.Duration(span) * time.Second,
+		Method:    method,
+		Header:    header,
+		OnStatus:  showStatus(cp),
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(-1)
 	}
+	go func() {
+		err := src.Run(context.Background(), func(frame source.Frame) {
+			cp.UpdateChan <- frame.Table
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read data from %s: %s\n", src.Name(), err)
+			cp.SetSourceError(err.Error())
+		}
+	}()
 
 	app.Run()
 }
 
-func PollCmd(command string, cp *cubeplane.CubePlane) {
-
-	// split out cmd and args
-	tmp := strings.Fields(command)
-	cmd := tmp[0]
-	args := strings.Join(tmp[1:], " ")
-
-	failed := func(err error) {
-		fmt.Fprintf(os.Stderr, "Command failed to run command %s %s: %s\n", cmd, args, err)
-		time.Sleep(3 * time.Second)
-	}
-
-	// main loop that polls the command
-	needsHeader := true
-	for {
-		run := exec.Command(cmd, args)
-		stdout, err := run.StdoutPipe()
-		if err != nil {
-			failed(err)
-			continue
-		}
-
-		if err := run.Start(); err != nil {
-			failed(err)
-			continue
+// sourceSpec returns the spec of the source given as an argument, or with
+// -c or -f.
+func sourceSpec(args []string) (string, error) {
+	given := 0
+	for _, s := range []string{command, file, strings.Join(args, " ")} {
+		if s != "" {
+			given++
 		}
+	}
+	switch {
+	case given == 0:
+		return "", errors.New("Please specify a source like cmd:\"ps aux\", or -c or -f to load data")
+	case given > 1:
+		return "", errors.New("Please specify only one of a source, -c or -f to load data")
+	case command != "":
+		return "cmd:" + command, nil
+	case file == "-":
+		return "stdin:", nil
+	case file != "":
+		return "file:" + file, nil
+	}
+	return strings.Join(args, " "), nil
+}
 
-		header, table, err := text2table.NewTable(stdout)
-		if err = run.Wait(); err != nil {
-			failed(err)
-			continue
+// showStatus returns a func that shows the status of a source in the HUD,
+// with the error it failed with if it's failing.
+func showStatus(cp *cubeplane.CubePlane) func(source.Status) {
+	return func(st source.Status) {
+		cp.SetSourceStatus(st.String())
+		if st.Failures == 0 {
+			cp.SetSourceError("")
+			return
 		}
-
-		if needsHeader {
-			cp.SetHeader(header)
-			needsHeader = false
+		if !st.Running {
+			fmt.Fprintf(os.Stderr, "Failed to load data from %s: %s\n", st.Name, st.LastError)
 		}
-
-		// send the table to the cube plane
-		cp.UpdateChan <- table
-		time.Sleep(3 * time.Second)
+		cp.SetSourceError(st.LastError.Error())
 	}
 }
 
-func PollFile(file string, cp *cubeplane.CubePlane) {
-
-	failed := func(err error) {
-		fmt.Fprintf(os.Stderr, "Failed to load data form file %s: %s\n", file, err)
-		time.Sleep(3 * time.Second)
-	}
-
-	needsHeader := true
-	for {
-		var input io.Reader
-		var fd *os.File
-		if file == "-" {
-			input = bufio.NewReader(os.Stdin)
-		} else {
-			fd, err := os.Open(file)
-			if err != nil {
-				failed(err)
-				fd.Close()
-				continue
-			}
-			input = bufio.NewReader(fd)
-		}
-
-		header, table, _ := text2table.NewTable(input)
-		if needsHeader {
-			cp.SetHeader(header)
-			needsHeader = false
+// keyColumns returns the columns given with --key or the key config option,
+// which may be a list or a comma separated string like the flag.
+func keyColumns() []string {
+	if s, ok := viper.Get("key").(string); ok {
+		if s == "" {
+			return nil
 		}
-		cp.UpdateChan <- table
-		time.Sleep(3 * time.Second)
-		fd.Close()
+		return strings.Split(s, ",")
 	}
+	return viper.GetStringSlice("key")
 }
@@ -17,9 +17,12 @@ package cubeplane
 import (
 	"fmt"
 	"math"
-	"strconv"
+	"reflect"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/cove/oview/pkg/text2table"
 	"golang.org/x/sync/semaphore"
 
 	"github.com/g3n/engine/camera/control"
@@ -60,26 +63,28 @@ type CubePlane struct {
 	backgroundColor    *math32.Color
 	hud                *Hud
 	rc                 *core.Raycaster
-	command            string
 	header             []string
+	key                []string
 	rotate             bool
 	UpdateChan         CubeUpdateChan
 	incomingInProgres  *semaphore.Weighted
 	timeout            chan bool
+	warnings           []string
+	sourceMu           sync.Mutex
+	sourceStatus       string
+	sourceErr          string
 }
 
-type CubeUpdateChan chan CubeUpdate
-type CubeUpdate [][]string
-type CubeHeader []string
+type CubeUpdateChan chan *text2table.Table
 type CubeData struct {
-	attrs  []string
+	row    text2table.Row
 	locX   int64
 	locY   int64
 	ttl    int64
 	active bool
 }
 
-func Init(app *application.Application, cmd string, refresh int,
+func Init(app *application.Application, refresh int,
 	wireframe bool, size int64, rotations int, pause bool, usage bool) *CubePlane {
 
 	// Add lights to the scene
@@ -134,7 +139,6 @@ func Init(app *application.Application, cmd string, refresh int,
 			color:    math32.NewColorHex(0xFCF2C6),
 			fontSize: float64(12.0),
 		},
-		command:           cmd,
 		rc:                core.NewRaycaster(&math32.Vector3{}, &math32.Vector3{}),
 		rotate:            !pause,
 		UpdateChan:        make(CubeUpdateChan, 1024),
@@ -174,6 +178,21 @@ func Init(app *application.Application, cmd string, refresh int,
 	return cp
 }
 
+// keyByName returns the indexes of the named columns in the table, and
+// whether it has all of them.
+func keyByName(table *text2table.Table, names []string) ([]int, bool) {
+	if names == nil {
+		return nil, false
+	}
+	key := make([]int, len(names))
+	for i, name := range names {
+		if key[i] = table.Column(name); key[i] < 0 {
+			return nil, false
+		}
+	}
+	return key, true
+}
+
 func (cp *CubePlane) processTimeout() {
 	for {
 		time.Sleep(1 * time.Second)
@@ -191,30 +210,106 @@ func (cp *CubePlane) processIncoming(i interface{}) {
 	select {
 	case table := <-cp.UpdateChan:
 
-		// use first value that's a number for scaling cubes
-		if cp.selectedHeaderIdx < 0 {
-			for i, v := range table[0] {
-				if _, err := strconv.ParseFloat(v, 64); err == nil {
+		// the columns that identify a cube are kept by name so cubes don't
+		// move if a later table infers them differently, until the header
+		// changes and they're not all in it anymore
+		header := table.Header()
+		key, ok := keyByName(table, cp.key)
+		if ok {
+			table.SetKey(key)
+		}
+		if !reflect.DeepEqual(header, cp.header) {
+			cp.key = make([]string, len(table.Key))
+			for i, k := range table.Key {
+				cp.key[i] = header[k]
+			}
+			if cp.selectedHeaderIdx >= 0 {
+				cp.selectedHeaderIdx = table.Column(cp.header[cp.selectedHeaderIdx])
+			}
+			cp.header = header
+			cp.clearHeaders()
+		}
+
+		// use first numeric column for scaling cubes
+		if cp.selectedHeaderIdx < 0 && len(table.Rows) > 0 {
+			for i, c := range table.Columns {
+				if c.Type == text2table.Numeric {
 					cp.selectedHeaderIdx = i
 					break
 				}
 			}
 			if cp.selectedHeaderIdx < 0 {
-				panic("no numbers found to scale cubes with")
+				for i, c := range table.Rows[0].Cells {
+					if c.Numeric {
+						cp.selectedHeaderIdx = i
+						break
+					}
+				}
 			}
 		}
+
+		// keep the cubes as they are until there's a column to scale them by
+		if cp.selectedHeaderIdx < 0 && len(table.Rows) > 0 {
+			cp.warnings = []string{"no numbers found to scale cubes with"}
+			cp.updateHud()
+			break
+		}
+
+		// rows with the same key would otherwise share a cube
+		var warnings []string
+		if n := len(table.Duplicates); n > 0 {
+			warnings = append(warnings, fmt.Sprintf("%d duplicate keys like %q, use --key to pick the columns that identify a row",
+				n, table.Duplicates[0]))
+		}
+		if table.Skipped > 0 {
+			warnings = append(warnings, fmt.Sprintf("%d lines skipped that didn't match --regexp", table.Skipped))
+		}
+		// aligned tables are the usual, say what else the fields were split on
+		if table.Format == text2table.FormatText && table.Separator != text2table.Whitespace {
+			warnings = append(warnings, fmt.Sprintf("fields separated by %q, use --separator to change", table.Separator))
+		}
+		cp.warnings = warnings
+
 		cp.updateHud()
 		cp.cullExpiredCubes()
-		for i := range table {
-			cp.updateCube(table[i][1], table[i])
+		for i := range table.Rows {
+			cp.updateCube(table.Rows[i].ID, table.Rows[i])
 		}
 
 	case <-cp.timeout:
 		break // timeout to prevent blocking
 	}
+
+	cp.sourceMu.Lock()
+	status := cp.warnings
+	if cp.sourceErr != "" {
+		status = append([]string{cp.sourceErr}, status...)
+	}
+	source := cp.sourceStatus
+	cp.sourceMu.Unlock()
+	cp.setStatus(strings.Join(status, "\n"), source)
+
 	cp.incomingInProgres.Release(1)
 }
 
+// SetSourceError shows why the data couldn't be read in the HUD, like the
+// last lines a failed command printed to stderr, or clears it when text is
+// empty. It's safe to call from any goroutine.
+func (cp *CubePlane) SetSourceError(text string) {
+	cp.sourceMu.Lock()
+	defer cp.sourceMu.Unlock()
+	cp.sourceErr = text
+}
+
+// SetSourceStatus shows a line about the health of the source of the data
+// in the HUD, like when it was last read. It's safe to call from any
+// goroutine.
+func (cp *CubePlane) SetSourceStatus(text string) {
+	cp.sourceMu.Lock()
+	defer cp.sourceMu.Unlock()
+	cp.sourceStatus = text
+}
+
 func (cp *CubePlane) updateSelectedCube() {
 
 	type matI interface {
@@ -244,7 +339,7 @@ func (cp *CubePlane) updateSelectedCube() {
 	cp.updateHud()
 }
 
-func (cp *CubePlane) updateCube(id string, attrs []string) {
+func (cp *CubePlane) updateCube(id string, row text2table.Row) {
 
 	// update exiting cube
 	for j := range cp.plane {
@@ -252,7 +347,7 @@ func (cp *CubePlane) updateCube(id string, attrs []string) {
 			node := cp.plane[i][j]
 			if node.Name() == id {
 				ud := node.UserData().(CubeData)
-				ud.attrs = attrs
+				ud.row = row
 				ud.ttl++
 				node.SetUserData(ud)
 				cp.updateCubeStatus(node)
@@ -271,7 +366,7 @@ func (cp *CubePlane) updateCube(id string, attrs []string) {
 			node := cp.plane[i][j]
 			if !isActive(node) {
 				ud := node.UserData().(CubeData)
-				ud.attrs = attrs
+				ud.row = row
 				ud.ttl = cp.ttl
 				node.SetUserData(ud)
 				node.SetName(id)
@@ -317,13 +412,16 @@ func (cp *CubePlane) updateCubeStatus(node *core.Node) {
 		ud := node.UserData().(CubeData)
 		imesh.SetColor(cp.cubeActiveColor)
 
-		value, err := strconv.ParseFloat(ud.attrs[cp.selectedHeaderIdx], 64)
-		if err != nil {
+		if cp.selectedHeaderIdx >= len(ud.row.Cells) {
+			return
+		}
+		cell := ud.row.Cells[cp.selectedHeaderIdx]
+		if !cell.Numeric {
 			return
 		}
 
 		// TODO: convert to percent, requires making a updateTable() call that can determine the max before rendering the cube
-		value = math.Log2(value)
+		value := math.Log2(cell.Value.Number)
 
 		imesh.SetWireframe(cp.cubeWireframe)
 
@@ -401,10 +499,6 @@ func makeActive(node *core.Node) {
 	node.SetUserData(ud)
 }
 
-func (cp *CubePlane) SetHeader(header CubeHeader) {
-	cp.header = header
-}
-
 func (cp *CubePlane) dumpPlane() {
 
 	fmt.Println("Dumping CubePlane:")
@@ -18,6 +18,7 @@ import (
 	"path"
 	"strings"
 
+	"github.com/cove/oview/pkg/text2table"
 	"github.com/g3n/engine/gui"
 	"github.com/g3n/engine/math32"
 )
@@ -29,6 +30,8 @@ type Hud struct {
 	headers  *gui.Panel
 	values   *gui.Panel
 	usage    *gui.Panel
+	status   *gui.Label
+	source   *gui.Label
 	buttons  []*gui.Button
 }
 
@@ -85,18 +88,63 @@ func (cp *CubePlane) initHud() {
 	cp.hud.usage.Add(usagetext)
 	cp.hud.main.Add(cp.hud.usage)
 
+	// status text for warnings about the data in lower left, above a line
+	// about the health of its source
+	cp.hud.status = gui.NewLabel("")
+	cp.hud.status.SetColor(math32.NewColor("Orange"))
+	cp.hud.main.Add(cp.hud.status)
+	cp.hud.source = gui.NewLabel("")
+	cp.hud.source.SetColor(cp.hud.color)
+	cp.hud.main.Add(cp.hud.source)
+	cp.placeStatus()
+
 	// reposition the usage panel on a screen resize
 	cp.app.Gui().Subscribe(gui.OnResize, func(evname string, ev interface{}) {
 		width, height := cp.app.Window().Size()
 		cp.hud.main.SetSize(float32(width), float32(height))
 		cp.hud.usage.SetPosition(float32(width)-340, float32(height)-350)
+		cp.placeStatus()
 	})
 }
 
+// setStatus shows warnings about the data and the status of its source in
+// the HUD, hiding either one when its text is empty.
+func (cp *CubePlane) setStatus(text string, source string) {
+	if text == cp.hud.status.Text() && source == cp.hud.source.Text() {
+		return
+	}
+	cp.hud.status.SetText(text)
+	cp.hud.status.SetVisible(text != "")
+	cp.hud.source.SetText(source)
+	cp.hud.source.SetVisible(source != "")
+	cp.placeStatus()
+}
+
+// placeStatus stacks the status lines up from the bottom of the screen.
+func (cp *CubePlane) placeStatus() {
+	_, height := cp.app.Window().Size()
+	y := float32(height) - 30
+	if cp.hud.source.Text() != "" {
+		y -= cp.hud.source.Height()
+	}
+	cp.hud.source.SetPosition(0, y)
+	cp.hud.status.SetPosition(0, y-cp.hud.status.Height())
+}
+
+// clearHeaders removes the header buttons so updateHud adds them again for a
+// table with different columns.
+func (cp *CubePlane) clearHeaders() {
+	for _, b := range cp.hud.buttons {
+		cp.hud.headers.Remove(b)
+		b.Dispose()
+	}
+	cp.hud.buttons = nil
+}
+
 func (cp *CubePlane) updateHud() {
 
-	// add headers
-	if cp.hud.headers.Root() == nil {
+	// add headers, again after the columns changed
+	if len(cp.hud.buttons) == 0 {
 		for i := range cp.header {
 			lineSpace := float32(8.0)
 			name := cp.header[i]
@@ -133,22 +181,37 @@ func (cp *CubePlane) updateHud() {
 			cp.hud.buttons = append(cp.hud.buttons, header)
 			cp.hud.headers.Add(header)
 		}
+
+		// the column picked before is still highlighted
+		if cp.selectedHeaderIdx >= 0 && cp.selectedHeaderIdx < len(cp.hud.buttons) {
+			cp.hud.buttons[cp.selectedHeaderIdx].SetStyles(&gui.ButtonStyles{
+				Over:   gui.ButtonStyle{FgColor: *math32.NewColor4("Gold", 1.0)},
+				Normal: gui.ButtonStyle{FgColor: *math32.NewColor4("Gold", 1.0)},
+			})
+		}
 		cp.hud.headers.SetTopChild(cp.hud.values)
+	}
+	if cp.hud.main.Root() == nil {
 		cp.app.Gui().Add(cp.hud.main)
 	}
 
 	// add values
 	node := cp.plane[cp.cursorX][cp.cursorY]
 	ud := node.UserData().(CubeData)
-	if ud.attrs == nil {
+	if ud.row.Cells == nil {
 		return
 	}
 
 	// display updated values
 	cp.hud.values.DisposeChildren(true)
-	for i := range ud.attrs {
+	for i, cell := range ud.row.Cells {
 		lineSpace := float32(8.0)
-		name := cleanCommandPaths(ud.attrs[i])
+		name := cleanCommandPaths(cell.Text)
+
+		// show the metric the cubes are scaled by in its unit
+		if i == cp.selectedHeaderIdx && cell.Numeric && (cell.Value.Unit != text2table.UnitNone || cell.Value.Ratio) {
+			name = cell.Value.String()
+		}
 		value := gui.NewLabel(name)
 		value.SetPosition(110, 20.0+(float32(i)*(float32(cp.hud.fontSize)+lineSpace)))
 		value.SetColor(math32.NewColor("White"))
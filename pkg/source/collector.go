@@ -0,0 +1,94 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/cove/oview/pkg/text2table"
+)
+
+// collectorSource reads a table from the system every interval, like the
+// processes in /proc, rather than parsing one from text.
+type collectorSource struct {
+	name    string
+	collect func() (*text2table.Table, error)
+	cfg     Config
+}
+
+func (s *collectorSource) Name() string {
+	return s.name
+}
+
+func (s *collectorSource) Run(ctx context.Context, emit func(Frame)) error {
+	s.cfg.supervisor(s.Name()).Run(ctx, func(ctx context.Context, alive func()) error {
+		table, err := s.collect()
+		if err != nil {
+			return err
+		}
+		emit(Frame{Table: table, Time: time.Now(), Source: s.Name()})
+		return nil
+	})
+	return nil
+}
+
+// tableBuilder makes the table of a collector, which knows the types and
+// units of its columns rather than inferring them.
+type tableBuilder struct {
+	t *text2table.Table
+}
+
+// newTableBuilder starts a table with the columns.
+func newTableBuilder(columns ...text2table.Column) *tableBuilder {
+	return &tableBuilder{t: &text2table.Table{Columns: columns}}
+}
+
+// add adds a row with a cell for each column.
+func (b *tableBuilder) add(cells ...text2table.Cell) {
+	b.t.Rows = append(b.t.Rows, text2table.Row{Cells: cells})
+}
+
+// table returns the table with its rows identified by the key columns.
+func (b *tableBuilder) table(key ...int) *text2table.Table {
+	b.t.SetKey(key)
+	return b.t
+}
+
+// textCell is a cell holding text, which is null when empty.
+func textCell(s string) text2table.Cell {
+	return text2table.Cell{Text: s, Null: s == ""}
+}
+
+// numberCell is a cell holding a number in a unit, rounded to a precision
+// worth showing, which is whole bytes for sizes and microseconds for
+// durations.
+func numberCell(n float64, unit text2table.Unit) text2table.Cell {
+	if unit == text2table.UnitBytes {
+		n = math.Round(n)
+	} else if unit == text2table.UnitSeconds {
+		n = math.Round(n*1e6) / 1e6
+	} else if n != math.Trunc(n) {
+		n = math.Round(n*100) / 100
+	}
+	v := text2table.Value{Number: n, Unit: unit}
+	return text2table.Cell{Text: v.String(), Value: v, Numeric: true}
+}
+
+// nullCell is a cell for a value that couldn't be read.
+func nullCell() text2table.Cell {
+	return text2table.Cell{Null: true}
+}
@@ -0,0 +1,291 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+func init() {
+	Register("cmd", newCommandSource)
+}
+
+// DefaultShell is the shell commands are run through unless another is
+// given.
+const DefaultShell = "sh -c"
+
+// Command is a command line to run, through a shell so pipes and quoting
+// work as they do in a terminal (e.g. `ps aux | grep java`), or split into
+// words and run directly when there's no shell.
+type Command struct {
+	// Line is the command line to run
+	Line string
+	// Shell is the shell and its flags the line is passed to, like "sh -c"
+	// or "bash -o pipefail -c"
+	Shell string
+	// Dir is the working directory, the current one when empty
+	Dir string
+}
+
+// Cmd returns the process that runs the command line. Its stdin is closed
+// and it gets the environment of oview.
+func (c Command) Cmd() (*exec.Cmd, error) {
+
+	var argv []string
+	if c.Shell != "" {
+		shell, err := SplitWords(c.Shell)
+		if err != nil {
+			return nil, fmt.Errorf("shell %q: %s", c.Shell, err)
+		}
+		argv = append(shell, c.Line)
+	} else {
+		words, err := SplitWords(c.Line)
+		if err != nil {
+			return nil, fmt.Errorf("command %q: %s", c.Line, err)
+		}
+		argv = words
+	}
+	if len(argv) == 0 || strings.TrimSpace(c.Line) == "" {
+		return nil, errors.New("no command to run")
+	}
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Dir = c.Dir
+	cmd.Env = os.Environ()
+	// a nil stdin reads from the null device, so commands that wait for
+	// input don't hang
+	cmd.Stdin = nil
+	setProcessGroup(cmd)
+	return cmd, nil
+}
+
+// Run runs the command once, passing its stdout to read and its stderr to
+// stderr. When ctx is done before the command is, its process group is
+// killed, which ends the output for read too.
+func (c Command) Run(ctx context.Context, stderr io.Writer, read func(stdout io.Reader)) error {
+
+	cmd, err := c.Cmd()
+	if err != nil {
+		return err
+	}
+	cmd.Stderr = stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			killProcessGroup(cmd)
+		case <-done:
+		}
+	}()
+
+	read(stdout)
+	// drain what read left, so the command isn't stuck writing to the pipe
+	io.Copy(ioutil.Discard, stdout)
+	return cmd.Wait()
+}
+
+// SplitWords splits a command line into words like a POSIX shell does,
+// without expanding anything. Words are separated by whitespace, single
+// quotes keep everything up to the next one, double quotes keep everything
+// but a backslash before one of $`"\ or a newline, and a backslash outside
+// of quotes keeps the character after it.
+func SplitWords(line string) ([]string, error) {
+
+	var words []string
+	var word strings.Builder
+	inWord := false
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+
+		case r == '\\':
+			inWord = true
+			i++
+			if i == len(runes) {
+				return nil, errors.New("backslash at the end")
+			}
+			// an escaped newline continues the line
+			if runes[i] != '\n' {
+				word.WriteRune(runes[i])
+			}
+
+		case r == '\'':
+			inWord = true
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			word.WriteString(string(runes[i+1 : end]))
+			i = end
+
+		case r == '"':
+			inWord = true
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("$`\"\\\n", runes[i+1]) {
+					i++
+					if runes[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, errors.New("unterminated double quote")
+			}
+
+		default:
+			inWord = true
+			word.WriteRune(r)
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
+
+// indexRune returns the index of the first r in runes at or after start, or
+// -1 if there's none.
+func indexRune(runes []rune, start int, r rune) int {
+	for i := start; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// Tail is a writer that keeps the last lines written to it, for showing
+// what a command printed to stderr before it failed.
+type Tail struct {
+	// Lines is how many lines to keep
+	Lines int
+
+	mu    sync.Mutex
+	lines []string
+	part  string
+}
+
+// Write adds the lines in p, dropping the oldest ones beyond the limit.
+func (t *Tail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	text := t.part + string(p)
+	parts := strings.Split(text, "\n")
+	t.part = parts[len(parts)-1]
+	for _, line := range parts[:len(parts)-1] {
+		t.lines = append(t.lines, strings.TrimRight(line, "\r"))
+	}
+	if len(t.lines) > t.Lines {
+		t.lines = t.lines[len(t.lines)-t.Lines:]
+	}
+
+	return len(p), nil
+}
+
+// String returns the lines kept, including an unterminated last line.
+func (t *Tail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines := t.lines
+	if t.part != "" {
+		lines = append(lines[:len(lines):len(lines)], t.part)
+		if len(lines) > t.Lines {
+			lines = lines[1:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Reset forgets the lines written so far.
+func (t *Tail) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lines, t.part = nil, ""
+}
+
+// commandSource runs a command every interval, emitting the tables it
+// prints as they come so commands that keep running like `vmstat 1` work
+// too.
+type commandSource struct {
+	command Command
+	cfg     Config
+}
+
+func newCommandSource(spec Spec, cfg Config) (Source, error) {
+	if strings.TrimSpace(spec.Arg) == "" {
+		return nil, errors.New("cmd: needs a command to run")
+	}
+	return &commandSource{command: Command{Line: spec.Arg, Shell: cfg.Shell, Dir: cfg.Dir}, cfg: cfg}, nil
+}
+
+func (s *commandSource) Name() string {
+	return s.command.Line
+}
+
+func (s *commandSource) Run(ctx context.Context, emit func(Frame)) error {
+
+	// the last lines of stderr are part of the error when the command fails
+	stderr := &Tail{Lines: 5}
+
+	// a command that keeps printing is still stopped at the deadline
+	sup := s.cfg.supervisor(s.Name())
+	sup.Deadline = s.cfg.Deadline
+
+	sup.Run(ctx, func(ctx context.Context, alive func()) error {
+		stderr.Reset()
+		var streamErr error
+		err := s.command.Run(ctx, stderr, func(stdout io.Reader) {
+			streamErr = streamTables(ctx, ProgressReader(stdout, alive), s.Name(), s.cfg, true, sup.warn, emit)
+		})
+		if err == nil {
+			err = streamErr
+		}
+		if text := stderr.String(); err != nil && text != "" {
+			err = fmt.Errorf("%s\n%s", err, text)
+		}
+		return err
+	})
+	return nil
+}
@@ -0,0 +1,162 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{line: "ps -eo pid,rss,comm", want: []string{"ps", "-eo", "pid,rss,comm"}},
+		{line: "  ps\taux  ", want: []string{"ps", "aux"}},
+		{line: "", want: nil},
+		{line: `grep 'a b' "c d"`, want: []string{"grep", "a b", "c d"}},
+		{line: `echo 'it''s'`, want: []string{"echo", "its"}},
+		{line: `echo "say \"hi\" \$HOME \n"`, want: []string{"echo", `say "hi" $HOME \n`}},
+		{line: `echo a\ b \'`, want: []string{"echo", "a b", "'"}},
+		{line: `echo '' ""`, want: []string{"echo", "", ""}},
+		{line: "echo a\\\nb", want: []string{"echo", "ab"}},
+		{line: `echo 'open`, wantErr: true},
+		{line: `echo "open`, wantErr: true},
+		{line: `echo \`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := SplitWords(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SplitWords() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitWords() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandCmd(t *testing.T) {
+	tests := []struct {
+		name    string
+		command Command
+		args    []string
+		wantErr bool
+	}{
+		{
+			name:    "shell",
+			command: Command{Line: "ps aux | grep java", Shell: DefaultShell},
+			args:    []string{"sh", "-c", "ps aux | grep java"},
+		},
+		{
+			name:    "shell with flags",
+			command: Command{Line: "false | true", Shell: "bash -o pipefail -c"},
+			args:    []string{"bash", "-o", "pipefail", "-c", "false | true"},
+		},
+		{
+			name:    "no shell",
+			command: Command{Line: "ps -eo 'pid,rss,comm'"},
+			args:    []string{"ps", "-eo", "pid,rss,comm"},
+		},
+		{
+			name:    "empty",
+			command: Command{Line: " ", Shell: DefaultShell},
+			wantErr: true,
+		},
+		{
+			name:    "bad quoting",
+			command: Command{Line: "ps 'aux"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, err := tt.command.Cmd()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Cmd() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(cmd.Args, tt.args) {
+				t.Errorf("Cmd() args = %q, want %q", cmd.Args, tt.args)
+			}
+			if cmd.Stdin != nil {
+				t.Errorf("Cmd() stdin = %v, want closed", cmd.Stdin)
+			}
+		})
+	}
+}
+
+func TestCommandRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oview")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("OVIEW_TEST", "passed")
+	defer os.Unsetenv("OVIEW_TEST")
+
+	cmd, err := Command{
+		Line:  `printf 'a b\n1 2\n' | tr ' ' ,; pwd; echo "$OVIEW_TEST"; cat; echo oops >&2`,
+		Shell: DefaultShell,
+		Dir:   dir,
+	}.Cmd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := &Tail{Lines: 5}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Output() error = %v", err)
+	}
+
+	wd, _ := os.Stat(dir)
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 4 || lines[0] != "a,b" || lines[1] != "1,2" || lines[3] != "passed" {
+		t.Fatalf("Output() = %q", out)
+	}
+	if got, _ := os.Stat(lines[2]); !os.SameFile(got, wd) {
+		t.Errorf("Output() ran in %s, want %s", lines[2], dir)
+	}
+	if stderr.String() != "oops" {
+		t.Errorf("stderr = %q, want %q", stderr.String(), "oops")
+	}
+}
+
+func TestTail(t *testing.T) {
+	tail := &Tail{Lines: 2}
+	for _, s := range []string{"one\ntw", "o\r\nthree\n", "fo"} {
+		tail.Write([]byte(s))
+	}
+	if got, want := tail.String(), "three\nfo"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	tail.Write([]byte("ur\n"))
+	if got, want := tail.String(), "three\nfour"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	tail.Reset()
+	if got := tail.String(); got != "" {
+		t.Errorf("String() after Reset() = %q, want empty", got)
+	}
+}
@@ -0,0 +1,336 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cove/oview/pkg/text2table"
+)
+
+func init() {
+	Register("docker", newDockerSource)
+}
+
+// dockerSocket is where the Docker daemon listens unless DOCKER_HOST says
+// otherwise.
+const dockerSocket = "/var/run/docker.sock"
+
+// dockerWorkers is how many containers' stats are asked for at once.
+const dockerWorkers = 8
+
+// newDockerSource makes a source of the running containers from the Docker
+// Engine API, on the unix socket in the spec like
+// "docker:/run/user/1000/docker.sock", in DOCKER_HOST, or the default one.
+func newDockerSource(spec Spec, cfg Config) (Source, error) {
+	socket := spec.Arg
+	if socket == "" {
+		socket = dockerSocket
+		if host := os.Getenv("DOCKER_HOST"); host != "" {
+			if !strings.HasPrefix(host, "unix://") {
+				return nil, fmt.Errorf("docker: only talks to a unix socket, not DOCKER_HOST=%s", host)
+			}
+			socket = strings.TrimPrefix(host, "unix://")
+		}
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	c := newDockerCollector(socket, timeout, cfg.Labels)
+	return &collectorSource{name: "docker:" + socket, collect: c.collect, cfg: cfg}, nil
+}
+
+// dockerCollector reads a table of the running containers and their usage
+// like `docker stats`, from the container list and a one-shot sample of the
+// stats of each container. The usage of CPU, network and disk is worked out
+// between samples.
+type dockerCollector struct {
+	client *http.Client
+	// labels shown as columns after the usage
+	labels []string
+	last   map[string]dockerSample
+}
+
+// dockerSample is what the rates of a container are worked out from.
+type dockerSample struct {
+	read     time.Time
+	cpu      uint64
+	system   uint64
+	netRx    uint64
+	netTx    uint64
+	blkRead  uint64
+	blkWrite uint64
+}
+
+// dockerContainer is a container in the list from /containers/json.
+type dockerContainer struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	Image  string            `json:"Image"`
+	Labels map[string]string `json:"Labels"`
+}
+
+// dockerCPU is the CPU time a container and the host have used, in
+// nanoseconds.
+type dockerCPU struct {
+	Usage struct {
+		Total uint64 `json:"total_usage"`
+	} `json:"cpu_usage"`
+	System uint64 `json:"system_cpu_usage"`
+	CPUs   int    `json:"online_cpus"`
+}
+
+// dockerStats are the stats of a container from /containers/<id>/stats.
+type dockerStats struct {
+	Read time.Time `json:"read"`
+	PIDs struct {
+		Current uint64 `json:"current"`
+	} `json:"pids_stats"`
+	CPU    dockerCPU `json:"cpu_stats"`
+	PreCPU dockerCPU `json:"precpu_stats"`
+	Memory struct {
+		Usage uint64            `json:"usage"`
+		Limit uint64            `json:"limit"`
+		Stats map[string]uint64 `json:"stats"`
+	} `json:"memory_stats"`
+	Networks map[string]struct {
+		Rx uint64 `json:"rx_bytes"`
+		Tx uint64 `json:"tx_bytes"`
+	} `json:"networks"`
+	Blkio struct {
+		Bytes []struct {
+			Op    string `json:"op"`
+			Value uint64 `json:"value"`
+		} `json:"io_service_bytes_recursive"`
+	} `json:"blkio_stats"`
+}
+
+func newDockerCollector(socket string, timeout time.Duration, labels []string) *dockerCollector {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}
+	return &dockerCollector{client: &http.Client{Transport: transport, Timeout: timeout}, labels: labels}
+}
+
+// dockerColumns are the columns every table of containers has, followed by
+// one for each label asked for.
+var dockerColumns = []text2table.Column{
+	{Name: "CONTAINER ID", Type: text2table.Identifier},
+	{Name: "NAME", Type: text2table.Identifier},
+	{Name: "IMAGE", Type: text2table.Categorical},
+	{Name: "%CPU", Type: text2table.Numeric, Unit: text2table.UnitPercent},
+	{Name: "MEM USAGE", Type: text2table.Numeric, Unit: text2table.UnitBytes},
+	{Name: "%MEM", Type: text2table.Numeric, Unit: text2table.UnitPercent},
+	{Name: "NET RX/s", Type: text2table.Numeric, Unit: text2table.UnitBytes},
+	{Name: "NET TX/s", Type: text2table.Numeric, Unit: text2table.UnitBytes},
+	{Name: "BLOCK READ/s", Type: text2table.Numeric, Unit: text2table.UnitBytes},
+	{Name: "BLOCK WRITE/s", Type: text2table.Numeric, Unit: text2table.UnitBytes},
+	{Name: "PIDS", Type: text2table.Numeric},
+}
+
+// collect reads the table of running containers.
+func (c *dockerCollector) collect() (*text2table.Table, error) {
+
+	var containers []dockerContainer
+	if err := c.get("/containers/json", &containers); err != nil {
+		return nil, err
+	}
+
+	// ask for the stats of several containers at once, as each one takes
+	// the daemon a while
+	stats := make([]*dockerStats, len(containers))
+	errs := make([]error, len(containers))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < dockerWorkers && w < len(containers); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				s := &dockerStats{}
+				errs[i] = c.get("/containers/"+containers[i].ID+"/stats?stream=false&one-shot=true", s)
+				stats[i] = s
+			}
+		}()
+	}
+	for i := range containers {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	// containers stop between the list and their stats, but when none of
+	// them could be read it's the daemon that's failing
+	var failed error
+	n := 0
+	for _, err := range errs {
+		if err != nil {
+			failed = err
+			n++
+		}
+	}
+	if n > 0 && n == len(containers) {
+		return nil, failed
+	}
+
+	// the labels asked for are columns, so they stay put whatever labels
+	// the containers come and go with
+	columns := append([]text2table.Column(nil), dockerColumns...)
+	for _, k := range c.labels {
+		columns = append(columns, text2table.Column{Name: k, Type: text2table.Categorical})
+	}
+
+	b := newTableBuilder(columns...)
+	last := make(map[string]dockerSample, len(containers))
+	for i, ct := range containers {
+		if errs[i] != nil {
+			// it stopped since the list was made
+			continue
+		}
+		s := stats[i]
+		sample := s.sample()
+		last[ct.ID] = sample
+
+		name := ct.ID
+		if len(ct.Names) > 0 {
+			name = strings.TrimPrefix(ct.Names[0], "/")
+		}
+		id := ct.ID
+		if len(id) > 12 {
+			id = id[:12]
+		}
+
+		// the page cache can be dropped, so it's not counted as used
+		// like in `docker stats`
+		mem := s.Memory.Usage
+		cache := s.Memory.Stats["inactive_file"]
+		if v, ok := s.Memory.Stats["cache"]; ok {
+			cache = v
+		}
+		if cache < mem {
+			mem -= cache
+		}
+		memPercent := nullCell()
+		if s.Memory.Limit > 0 {
+			memPercent = numberCell(float64(mem)/float64(s.Memory.Limit)*100, text2table.UnitPercent)
+		}
+
+		cells := []text2table.Cell{
+			textCell(id),
+			textCell(name),
+			textCell(ct.Image),
+			c.cpuPercent(ct.ID, s, sample),
+			numberCell(float64(mem), text2table.UnitBytes),
+			memPercent,
+		}
+		cells = append(cells, c.rates(ct.ID, sample)...)
+		cells = append(cells, numberCell(float64(s.PIDs.Current), text2table.UnitNone))
+		for _, k := range c.labels {
+			cells = append(cells, textCell(ct.Labels[k]))
+		}
+		b.add(cells...)
+	}
+	c.last = last
+
+	return b.table(0), nil
+}
+
+// sample returns the counters of the stats the rates are worked out from.
+func (s *dockerStats) sample() dockerSample {
+	sample := dockerSample{read: s.Read, cpu: s.CPU.Usage.Total, system: s.CPU.System}
+	for _, n := range s.Networks {
+		sample.netRx += n.Rx
+		sample.netTx += n.Tx
+	}
+	for _, b := range s.Blkio.Bytes {
+		switch strings.ToLower(b.Op) {
+		case "read":
+			sample.blkRead += b.Value
+		case "write":
+			sample.blkWrite += b.Value
+		}
+	}
+	return sample
+}
+
+// cpuPercent returns the share of the CPUs a container used since the
+// last sample, or since the sample before the stats when there are two in
+// them, as 100% per CPU like `docker stats`. It's null the first time.
+func (c *dockerCollector) cpuPercent(id string, s *dockerStats, sample dockerSample) text2table.Cell {
+	cpu, system := s.PreCPU.Usage.Total, s.PreCPU.System
+	if last, ok := c.last[id]; ok {
+		cpu, system = last.cpu, last.system
+	}
+	if system == 0 || sample.system <= system || sample.cpu < cpu {
+		return nullCell()
+	}
+	cpus := s.CPU.CPUs
+	if cpus == 0 {
+		cpus = 1
+	}
+	return numberCell(float64(sample.cpu-cpu)/float64(sample.system-system)*float64(cpus)*100, text2table.UnitPercent)
+}
+
+// rates returns the network received and sent, and disk read and written
+// per second since the last sample, which are null the first time.
+func (c *dockerCollector) rates(id string, sample dockerSample) []text2table.Cell {
+	last, ok := c.last[id]
+	elapsed := sample.read.Sub(last.read).Seconds()
+	cells := make([]text2table.Cell, 4)
+	for i, n := range [][2]uint64{
+		{sample.netRx, last.netRx}, {sample.netTx, last.netTx},
+		{sample.blkRead, last.blkRead}, {sample.blkWrite, last.blkWrite},
+	} {
+		if !ok || elapsed <= 0 || n[0] < n[1] {
+			cells[i] = nullCell()
+			continue
+		}
+		cells[i] = numberCell(float64(n[0]-n[1])/elapsed, text2table.UnitBytes)
+	}
+	return cells
+}
+
+// get gets a path of the API and decodes its JSON into v.
+func (c *dockerCollector) get(path string, v interface{}) error {
+	resp, err := c.client.Get("http://docker" + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var msg struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&msg)
+		if msg.Message == "" {
+			msg.Message = resp.Status
+		}
+		return fmt.Errorf("docker %s: %s", path, msg.Message)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,162 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDocker serves the API of a Docker daemon on a unix socket from the
+// JSON files in a snapshot directory, failing the stats calls if asked to.
+type fakeDocker struct {
+	mu        sync.Mutex
+	snapshot  string
+	failStats bool
+}
+
+func (d *fakeDocker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	snapshot, failStats := d.snapshot, d.failStats
+	d.mu.Unlock()
+
+	file := ""
+	switch {
+	case r.URL.Path == "/containers/json":
+		file = "containers.json"
+	case strings.HasSuffix(r.URL.Path, "/stats"):
+		if r.URL.Query().Get("stream") != "false" {
+			http.Error(w, `{"message": "streaming stats"}`, http.StatusBadRequest)
+			return
+		}
+		if failStats {
+			http.Error(w, `{"message": "daemon is shutting down"}`, http.StatusInternalServerError)
+			return
+		}
+		file = path.Base(path.Dir(r.URL.Path)) + ".json"
+	}
+	data, err := ioutil.ReadFile(filepath.Join(snapshot, file))
+	if file == "" || err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "No such container"}`))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// serveDocker serves the fake daemon on a socket in a temporary directory,
+// returning its path and a func that stops it.
+func serveDocker(t *testing.T, daemon *fakeDocker) (string, func()) {
+	dir, err := ioutil.TempDir("", "oview")
+	if err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(dir, "docker.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: daemon}
+	go server.Serve(l)
+	return socket, func() {
+		server.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestDockerCollector(t *testing.T) {
+	daemon := &fakeDocker{}
+	socket, stop := serveDocker(t, daemon)
+	defer stop()
+
+	header := []string{
+		"CONTAINER ID", "NAME", "IMAGE", "%CPU", "MEM USAGE", "%MEM",
+		"NET RX/s", "NET TX/s", "BLOCK READ/s", "BLOCK WRITE/s", "PIDS",
+		"com.docker.compose.service", "tier",
+	}
+	// the first sample has rates only where the daemon gave the stats
+	// before, then they're worked out since the first; the container that
+	// stopped before its stats were read is left out
+	samples := [][][]string{
+		{
+			{"4f1a2b3c4d5e", "web", "nginx:1.25", "100%", "96.0 MiB", "9.38%", "", "", "", "", "3", "web", ""},
+			{"9e8d7c6b5a4f", "db", "postgres:16", "", "48.0 MiB", "", "", "", "", "", "7", "db", "backend"},
+		},
+		{
+			{"4f1a2b3c4d5e", "web", "nginx:1.25", "50%", "96.0 MiB", "9.38%", "1.0 KiB", "0 B", "1.0 MiB", "0 B", "4", "web", ""},
+			{"9e8d7c6b5a4f", "db", "postgres:16", "40%", "48.0 MiB", "", "0 B", "0 B", "0 B", "0 B", "7", "db", "backend"},
+		},
+	}
+
+	c := newDockerCollector(socket, time.Second, []string{"com.docker.compose.service", "tier"})
+	for i, want := range samples {
+		daemon.mu.Lock()
+		daemon.snapshot = fmt.Sprintf("testdata/docker/t%d", i)
+		daemon.mu.Unlock()
+
+		table, err := c.collect()
+		if err != nil {
+			t.Fatalf("sample %d: error = %v", i, err)
+		}
+		if got := table.Header(); !reflect.DeepEqual(got, header) {
+			t.Errorf("sample %d: header = %q, want %q", i, got, header)
+		}
+		if got := cellTexts(table); !reflect.DeepEqual(got, want) {
+			t.Errorf("sample %d: rows =\n%q\nwant\n%q", i, got, want)
+		}
+		if len(table.Rows) > 0 && table.Rows[0].ID != "4f1a2b3c4d5e" {
+			t.Errorf("sample %d: row id = %q, want the container id", i, table.Rows[0].ID)
+		}
+	}
+}
+
+func TestDockerCollectorStatsFail(t *testing.T) {
+	daemon := &fakeDocker{snapshot: "testdata/docker/t1", failStats: true}
+	socket, stop := serveDocker(t, daemon)
+	defer stop()
+
+	// no stats at all is an error rather than a table without containers
+	c := newDockerCollector(socket, time.Second, nil)
+	table, err := c.collect()
+	if err == nil || !strings.Contains(err.Error(), "daemon is shutting down") {
+		t.Errorf("collect() = %v, %v, want the stats error", table, err)
+	}
+}
+
+func TestDockerHost(t *testing.T) {
+	defer os.Setenv("DOCKER_HOST", os.Getenv("DOCKER_HOST"))
+
+	os.Setenv("DOCKER_HOST", "unix:///run/user/1000/docker.sock")
+	src, err := New("docker:", Config{})
+	if err != nil || src.Name() != "docker:/run/user/1000/docker.sock" {
+		t.Errorf("New() = %v, %v, want the socket in DOCKER_HOST", src, err)
+	}
+	os.Setenv("DOCKER_HOST", "tcp://10.0.0.1:2375")
+	if _, err := New("docker:", Config{}); err == nil {
+		t.Error("New() with a tcp DOCKER_HOST succeeded, want an error")
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/cove/oview/pkg/text2table"
+)
+
+func init() {
+	Register("file", newFileSource)
+	Register("stdin", newStdinSource)
+}
+
+// fileSource reads a table from a file whenever it changes, or follows the
+// lines appended to it.
+type fileSource struct {
+	path string
+	cfg  Config
+}
+
+func newFileSource(spec Spec, cfg Config) (Source, error) {
+	if spec.Arg == "" {
+		return nil, errors.New("file: needs the path of a file to read")
+	}
+	return &fileSource{path: spec.Arg, cfg: cfg}, nil
+}
+
+func (s *fileSource) Name() string {
+	return s.path
+}
+
+func (s *fileSource) Run(ctx context.Context, emit func(Frame)) error {
+
+	// read the file when it changes, or every interval if it can't be
+	// watched
+	sup := s.cfg.supervisor(s.Name())
+	if wake, err := watchFile(ctx, s.path); err == nil {
+		sup.Wake = wake
+	} else {
+		fmt.Fprintf(os.Stderr, "Failed to watch %s, reading it every interval instead: %s\n", s.path, err)
+	}
+
+	read := s.read
+	if s.cfg.Follow {
+		f := newFollower(s.path, s.cfg.Options, s.cfg.Window)
+		defer f.close()
+		read = f.read
+	}
+
+	sup.Run(ctx, func(ctx context.Context, alive func()) error {
+		table, err := read()
+		if err != nil {
+			return err
+		}
+		if table != nil {
+			emit(Frame{Table: table, Time: time.Now(), Source: s.Name()})
+		}
+		return nil
+	})
+	return nil
+}
+
+// read reads the table in the whole file.
+func (s *fileSource) read() (*text2table.Table, error) {
+	fd, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	return text2table.ReadTable(bufio.NewReader(fd), s.cfg.Options)
+}
+
+// stdinSource reads the tables on stdin as they come until it's closed, as
+// it can only be read once.
+type stdinSource struct {
+	r   io.Reader
+	cfg Config
+}
+
+func newStdinSource(spec Spec, cfg Config) (Source, error) {
+	return &stdinSource{r: os.Stdin, cfg: cfg}, nil
+}
+
+func (s *stdinSource) Name() string {
+	return "stdin"
+}
+
+func (s *stdinSource) Run(ctx context.Context, emit func(Frame)) error {
+	warn := func(err error) { s.cfg.warn(s.Name(), err) }
+	return streamTables(ctx, s.r, s.Name(), s.cfg, false, warn, emit)
+}
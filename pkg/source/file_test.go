@@ -0,0 +1,210 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cove/oview/pkg/text2table"
+)
+
+func TestWatchFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oview")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "status.csv")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed, err := watchFile(ctx, path)
+	if err != nil {
+		t.Skipf("can't watch files here: %s", err)
+	}
+
+	expect := func(what string, want bool) {
+		t.Helper()
+		select {
+		case <-changed:
+			if !want {
+				t.Errorf("%s: changed, want no change", what)
+			}
+		case <-time.After(10 * watchDelay):
+			if want {
+				t.Errorf("%s: no change, want changed", what)
+			}
+		}
+	}
+
+	write := func(name, text string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("status.csv", "a,b\n1,2\n")
+	expect("created", true)
+
+	write("status.csv", "a,b\n1,3\n")
+	expect("written", true)
+
+	write("other.csv", "a,b\n")
+	expect("other file written", false)
+
+	write("status.csv.tmp", "a,b\n1,4\n")
+	if err := os.Rename(filepath.Join(dir, "status.csv.tmp"), path); err != nil {
+		t.Fatal(err)
+	}
+	expect("replaced", true)
+}
+
+func TestFollower(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oview")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "jobs.txt")
+
+	appendTo := func(text string) {
+		fd, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fd.WriteString(text)
+		fd.Close()
+	}
+
+	tests := []struct {
+		name   string
+		window int
+		change func()
+		ids    []string
+	}{
+		{
+			name:   "header and lines",
+			change: func() { appendTo("Jobs at 10:00\n\nJOB   DONE\nbuild 3\ntest  5\n") },
+			ids:    []string{"build", "test"},
+		},
+		{
+			name:   "half a line",
+			change: func() { appendTo("lint  ") },
+		},
+		{
+			name:   "rest of the line",
+			change: func() { appendTo("7\n") },
+			ids:    []string{"build", "test", "lint"},
+		},
+		{
+			name:   "window",
+			window: 2,
+			change: func() { appendTo("vet   1\n") },
+			ids:    []string{"lint", "vet"},
+		},
+		{
+			name:   "truncated",
+			window: 2,
+			change: func() { ioutil.WriteFile(path, []byte("pack  9\n"), 0644) },
+			ids:    []string{"vet", "pack"},
+		},
+		{
+			name:   "rotated",
+			window: 3,
+			change: func() {
+				appendTo("ship  2\n")
+				os.Rename(path, path+".1")
+				appendTo("JOB   DONE\ndocs  4\n")
+			},
+			ids: []string{"pack", "ship", "docs"},
+		},
+		{
+			name:   "nothing new",
+			window: 3,
+			change: func() {},
+		},
+	}
+
+	f := newFollower(path, text2table.Options{}, 0)
+	defer f.close()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f.window = tt.window
+			tt.change()
+			table, err := f.read()
+			if err != nil {
+				t.Fatalf("read() error = %v", err)
+			}
+			if tt.ids == nil {
+				if table != nil {
+					t.Errorf("read() = %d rows, want none new", len(table.Rows))
+				}
+				return
+			}
+			if table == nil {
+				t.Fatalf("read() = nil, want %q", tt.ids)
+			}
+			if got := table.Header(); !reflect.DeepEqual(got, []string{"JOB", "DONE"}) {
+				t.Errorf("read() header = %q", got)
+			}
+			var ids []string
+			for _, row := range table.Rows {
+				ids = append(ids, row.Cells[0].Text)
+			}
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("read() rows = %q, want %q", ids, tt.ids)
+			}
+		})
+	}
+}
+
+func TestFileSourceWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oview")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "jobs.csv")
+	ioutil.WriteFile(path, []byte("job,done\nbuild,3\n"), 0644)
+
+	// with an interval this long only a change can bring the second frame
+	src, err := New("file:"+path, Config{Interval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	frames := make(chan Frame)
+	go src.Run(ctx, func(frame Frame) { frames <- frame })
+
+	for i, want := range []int{1, 2} {
+		select {
+		case frame := <-frames:
+			if len(frame.Table.Rows) != want {
+				t.Errorf("frame %d: %d rows, want %d", i, len(frame.Table.Rows), want)
+			}
+		case <-ctx.Done():
+			t.Fatalf("frame %d: none, want %d rows", i, want)
+		}
+		ioutil.WriteFile(path, []byte("job,done\nbuild,3\ntest,5\n"), 0644)
+	}
+}
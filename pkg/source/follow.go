@@ -0,0 +1,208 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/cove/oview/pkg/text2table"
+)
+
+// DefaultWindow is how many of the last lines of a file followed are shown
+// by default, which bounds the lines parsed again on every change.
+const DefaultWindow = 1000
+
+// follower tails a file, keeping the lines appended to it under the header
+// it started with.
+type follower struct {
+	tail *tailer
+	opts text2table.Options
+	// window is how many of the last lines to keep, all of them when it's
+	// not positive
+	window int
+
+	headed bool
+	header string
+	lines  []string
+}
+
+// newFollower returns a follower of the file at path, which hasn't been
+// opened yet, keeping the last window lines, DefaultWindow when zero or all
+// of them when negative.
+func newFollower(path string, opts text2table.Options, window int) *follower {
+	if window == 0 {
+		window = DefaultWindow
+	}
+	return &follower{tail: &tailer{path: path}, opts: opts, window: window}
+}
+
+// read reads the lines appended since the last read, returning the table
+// of the lines kept, or nil if there are no new ones.
+func (f *follower) read() (*text2table.Table, error) {
+
+	lines, err := f.tail.next()
+	if err != nil {
+		return nil, err
+	}
+
+	n := 0
+	for _, line := range lines {
+		if line == "" || line == f.header {
+			continue
+		}
+		f.lines = append(f.lines, line)
+		n++
+	}
+	if n == 0 || len(f.lines) == 0 {
+		return nil, nil
+	}
+
+	if !f.headed {
+		f.headed = true
+		f.findHeader()
+	}
+	if f.window > 0 && len(f.lines) > f.window {
+		f.lines = append([]string(nil), f.lines[len(f.lines)-f.window:]...)
+	}
+	if len(f.lines) == 0 {
+		return nil, nil
+	}
+
+	text := strings.Join(f.lines, "\n")
+	if f.header != "" {
+		text = f.header + "\n" + text
+	}
+	return text2table.ReadTable(strings.NewReader(text), f.opts)
+}
+
+// findHeader takes the header out of the first lines read when the format
+// has one, pinning the format so later windows of lines are read the same
+// way. A header that shows up again, like at the top of a rotated file,
+// is skipped.
+func (f *follower) findHeader() {
+
+	format := f.opts.Format
+	if format == "" || format == text2table.FormatAuto {
+		format = text2table.FormatText
+		if f.opts.Separator == 0 {
+			format, f.opts.Separator = text2table.SniffFormat([]byte(strings.Join(f.lines, "\n")), f.opts.Comment)
+		}
+		f.opts.Format = format
+	}
+
+	switch {
+	case f.opts.Regexp != "":
+		return
+	case format == text2table.FormatText, format == text2table.FormatCSV, format == text2table.FormatTSV:
+		start, _ := text2table.FindTable(f.lines)
+		f.header = f.lines[start]
+		f.lines = f.lines[start+1:]
+	}
+}
+
+// close closes the file being followed.
+func (f *follower) close() {
+	f.tail.close()
+}
+
+// tailer reads the lines appended to a file like `tail -F`. It reads on
+// from where it left off, from the start again when the file is truncated,
+// and from the start of the new file when it's replaced or rotated.
+type tailer struct {
+	path string
+	// fromEnd skips what's in the file when it's first opened
+	fromEnd bool
+
+	fd      *os.File
+	info    os.FileInfo
+	offset  int64
+	partial []byte
+	opened  bool
+}
+
+// next returns the lines appended since the last call, without the last one
+// until it's complete.
+func (t *tailer) next() ([]string, error) {
+
+	info, err := os.Stat(t.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	if t.fd != nil && !os.SameFile(info, t.info) {
+		// replaced or rotated, finish the old file before the new one
+		lines, _ = t.readMore()
+		t.fd.Close()
+		t.fd = nil
+	}
+	if t.fd == nil {
+		fd, err := os.Open(t.path)
+		if err != nil {
+			return nil, err
+		}
+		t.fd, t.info, t.offset, t.partial = fd, info, 0, nil
+		if t.fromEnd && !t.opened {
+			if t.offset, err = fd.Seek(0, io.SeekEnd); err != nil {
+				return nil, err
+			}
+		}
+		t.opened = true
+	} else if info.Size() < t.offset {
+		if _, err := t.fd.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		t.offset, t.partial = 0, nil
+	}
+
+	more, err := t.readMore()
+	return append(lines, more...), err
+}
+
+// readMore reads to the end of the file, returning the lines it completed.
+func (t *tailer) readMore() ([]string, error) {
+
+	data, err := ioutil.ReadAll(t.fd)
+	t.offset += int64(len(data))
+	if err != nil || len(data) == 0 {
+		return nil, err
+	}
+
+	// the last line waits for the rest of it
+	data = append(t.partial, data...)
+	end := bytes.LastIndexByte(data, '\n') + 1
+	t.partial = append([]byte(nil), data[end:]...)
+	if end == 0 {
+		return nil, nil
+	}
+
+	lines := strings.Split(string(data[:end-1]), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, "\r")
+	}
+	return lines, nil
+}
+
+// close closes the file.
+func (t *tailer) close() {
+	if t.fd != nil {
+		t.fd.Close()
+		t.fd = nil
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cove/oview/pkg/text2table"
+)
+
+func init() {
+	Register("http", newHTTPSource)
+	Register("https", newHTTPSource)
+}
+
+// contentFormats are the formats of the tables served with a content type,
+// which are read as is rather than sniffed.
+var contentFormats = map[string]text2table.Format{
+	"application/json":          text2table.FormatJSON,
+	"application/x-ndjson":      text2table.FormatJSON,
+	"text/csv":                  text2table.FormatCSV,
+	"text/tab-separated-values": text2table.FormatTSV,
+}
+
+// httpSource requests a table from a URL every interval, like a JSON admin
+// endpoint, a CSV export or Prometheus metrics.
+type httpSource struct {
+	url    string
+	method string
+	client *http.Client
+	cfg    Config
+}
+
+func newHTTPSource(spec Spec, cfg Config) (Source, error) {
+	u, err := url.Parse(spec.String())
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%s: needs a URL like %s://localhost:8080/status", spec.Scheme, spec.Scheme)
+	}
+	method := strings.ToUpper(cfg.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	return &httpSource{url: u.String(), method: method, client: &http.Client{}, cfg: cfg}, nil
+}
+
+func (s *httpSource) Name() string {
+	return s.url
+}
+
+func (s *httpSource) Run(ctx context.Context, emit func(Frame)) error {
+	s.cfg.supervisor(s.Name()).Run(ctx, func(ctx context.Context, alive func()) error {
+		table, err := s.read(ctx, alive)
+		if err != nil {
+			return err
+		}
+		emit(Frame{Table: table, Time: time.Now(), Source: s.Name()})
+		return nil
+	})
+	return nil
+}
+
+// read requests the table, calling alive as the response comes in.
+func (s *httpSource) read(ctx context.Context, alive func()) (*text2table.Table, error) {
+
+	req, err := http.NewRequest(s.method, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for name, values := range s.cfg.Header {
+		req.Header[name] = append([]string(nil), values...)
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json, text/csv, text/plain, */*")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	alive()
+
+	body := ProgressReader(resp.Body, alive)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// the start of the body usually says what went wrong
+		text, _ := ioutil.ReadAll(io.LimitReader(body, 512))
+		err := fmt.Errorf("%s %s: %s", s.method, s.url, resp.Status)
+		if msg := strings.TrimSpace(string(text)); msg != "" {
+			err = fmt.Errorf("%s\n%s", err, msg)
+		}
+		return nil, err
+	}
+
+	opts := s.cfg.Options
+	if (opts.Format == "" || opts.Format == text2table.FormatAuto) && opts.Select == "" {
+		if t, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+			if format, ok := contentFormats[t]; ok {
+				opts.Format = format
+			}
+		}
+	}
+	return text2table.ReadTable(body, opts)
+}
+
+// ParseHeader parses header lines like "Authorization: Bearer x", as given
+// to curl -H.
+func ParseHeader(lines []string) (http.Header, error) {
+	header := http.Header{}
+	for _, line := range lines {
+		i := strings.Index(line, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("header %q isn't like \"Name: value\"", line)
+		}
+		header.Add(strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]))
+	}
+	return header, nil
+}
@@ -0,0 +1,141 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cove/oview/pkg/text2table"
+)
+
+func TestHTTPSource(t *testing.T) {
+	// the endpoints want a token, and /jobs wants a POST
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(`{"status": "ok", "data": {"servers": [{"name": "web1", "up": true}, {"name": "web2", "up": false}]}}`))
+	})
+	mux.HandleFunc("/export", func(w http.ResponseWriter, r *http.Request) {
+		// sniffed this would be text split on whitespace
+		w.Header().Set("Content-Type", "text/csv")
+		w.Write([]byte("host name,load\nweb 1,0.5\nweb 2,1.5\n"))
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write([]byte("# TYPE up gauge\nup{job=\"web\"} 1\nup{job=\"db\"} 0\n"))
+	})
+	mux.HandleFunc("/jobs", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "use POST", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Write([]byte("JOB   STATE\nbuild running\ntest  queued\n"))
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "secret" {
+			http.Error(w, "bad token", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	token := http.Header{"X-Token": {"secret"}}
+
+	tests := []struct {
+		name    string
+		path    string
+		cfg     Config
+		header  []string
+		rows    [][]string
+		wantErr string
+	}{
+		{
+			name:   "selected json",
+			path:   "/status",
+			cfg:    Config{Header: token, Options: text2table.Options{Select: "data.servers"}},
+			header: []string{"name", "up"},
+			rows:   [][]string{{"web1", "true"}, {"web2", "false"}},
+		},
+		{
+			name:   "csv content type",
+			path:   "/export",
+			cfg:    Config{Header: token},
+			header: []string{"host name", "load"},
+			rows:   [][]string{{"web 1", "0.5"}, {"web 2", "1.5"}},
+		},
+		{
+			name:   "sniffed",
+			path:   "/metrics",
+			cfg:    Config{Header: token},
+			header: []string{"series", "job", "value"},
+			rows:   [][]string{{`up{job="web"}`, "web", "1"}, {`up{job="db"}`, "db", "0"}},
+		},
+		{
+			name:   "method",
+			path:   "/jobs",
+			cfg:    Config{Header: token, Method: "post"},
+			header: []string{"JOB", "STATE"},
+			rows:   [][]string{{"build", "running"}, {"test", "queued"}},
+		},
+		{
+			name:    "failed",
+			path:    "/status",
+			wantErr: "401 Unauthorized\nbad token",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := New(server.URL+tt.path, tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			table, err := src.(*httpSource).read(context.Background(), func() {})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("read() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("read() error = %v", err)
+			}
+			if got := table.Header(); !reflect.DeepEqual(got, tt.header) {
+				t.Errorf("read() header = %q, want %q", got, tt.header)
+			}
+			if got := cellTexts(table); !reflect.DeepEqual(got, tt.rows) {
+				t.Errorf("read() rows = %q, want %q", got, tt.rows)
+			}
+		})
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	header, err := ParseHeader([]string{"Authorization: Bearer a:b", "X-Tag: 1", "x-tag:2"})
+	if err != nil {
+		t.Fatalf("ParseHeader() error = %v", err)
+	}
+	want := http.Header{"Authorization": {"Bearer a:b"}, "X-Tag": {"1", "2"}}
+	if !reflect.DeepEqual(header, want) {
+		t.Errorf("ParseHeader() = %q, want %q", header, want)
+	}
+	if _, err := ParseHeader([]string{"no colon"}); err == nil {
+		t.Error("ParseHeader() succeeded on a line without a colon")
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register("influx", func(spec Spec, cfg Config) (Source, error) {
+		return newMetricsSource(spec, cfg, "udp://127.0.0.1:8089", parseInflux)
+	})
+}
+
+// parseInflux parses a point in the InfluxDB line protocol like
+// `cpu,host=web1 usage=0.5,state="ok" 1556813561098000000` into a gauge for
+// each field. The timestamp is ignored, as points are shown as they come.
+func parseInflux(line string) ([]point, error) {
+
+	key, rest := splitUnescaped(line, ' ', false)
+	fieldSet, _ := splitUnescaped(strings.TrimLeft(rest, " "), ' ', true)
+	if key == "" || fieldSet == "" {
+		return nil, fmt.Errorf("influx point %q isn't like measurement,tag=value field=value", line)
+	}
+
+	// the measurement and its tags
+	var tags []metricTag
+	name, rest := splitUnescaped(key, ',', false)
+	for rest != "" {
+		var tag string
+		tag, rest = splitUnescaped(rest, ',', false)
+		k, v := splitUnescaped(tag, '=', false)
+		if k == "" {
+			return nil, fmt.Errorf("influx point %q has an invalid tag %q", line, tag)
+		}
+		tags = append(tags, metricTag{key: unescapeInflux(k), value: unescapeInflux(v)})
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].key < tags[j].key })
+	name = unescapeInflux(name)
+
+	var points []point
+	for rest = fieldSet; rest != ""; {
+		var field string
+		field, rest = splitUnescaped(rest, ',', true)
+		k, v := splitUnescaped(field, '=', true)
+		if k == "" || v == "" {
+			return nil, fmt.Errorf("influx point %q has an invalid field %q", line, field)
+		}
+
+		p := point{name: name, tags: tags, field: unescapeInflux(k), kind: gauge}
+		switch {
+		case strings.HasPrefix(v, `"`):
+			s, err := strconv.Unquote(v)
+			if err != nil {
+				s = strings.Trim(v, `"`)
+			}
+			p.text = s
+		case v == "t" || v == "T" || strings.EqualFold(v, "true"):
+			p.text = "true"
+		case v == "f" || v == "F" || strings.EqualFold(v, "false"):
+			p.text = "false"
+		default:
+			n, err := strconv.ParseFloat(strings.TrimRight(v, "iu"), 64)
+			if err != nil {
+				return nil, fmt.Errorf("influx point %q has an invalid value %q", line, v)
+			}
+			p.value = n
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// splitUnescaped splits s at the first sep that isn't escaped with a
+// backslash, or in double quotes if quotes are honored.
+func splitUnescaped(s string, sep byte, quotes bool) (string, string) {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case s[i] == '"' && quotes:
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			return s[:i], s[i+1:]
+		}
+	}
+	return s, ""
+}
+
+// unescapeInflux drops the backslashes escaping commas, spaces and equal
+// signs in names and tags.
+func unescapeInflux(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`, ="\`, s[i+1]) >= 0 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,67 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseInflux(t *testing.T) {
+	web1 := []metricTag{{"host", "web1"}, {"region", "eu west"}}
+	tests := []struct {
+		name    string
+		line    string
+		want    []point
+		wantErr bool
+	}{
+		{
+			name: "fields",
+			line: `cpu,region=eu\ west,host=web1 usage=0.5,cores=4i,state="ok, mostly",up=t 1556813561098000000`,
+			want: []point{
+				{name: "cpu", tags: web1, field: "usage", kind: gauge, value: 0.5},
+				{name: "cpu", tags: web1, field: "cores", kind: gauge, value: 4},
+				{name: "cpu", tags: web1, field: "state", kind: gauge, text: "ok, mostly"},
+				{name: "cpu", tags: web1, field: "up", kind: gauge, text: "true"},
+			},
+		},
+		{
+			name: "no tags or timestamp",
+			line: `disk\,io reads=12`,
+			want: []point{{name: "disk,io", field: "reads", kind: gauge, value: 12}},
+		},
+		{
+			name:    "no fields",
+			line:    "cpu,host=web1",
+			wantErr: true,
+		},
+		{
+			name:    "invalid value",
+			line:    "cpu usage=high",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseInflux(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseInflux() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseInflux() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
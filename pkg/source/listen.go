@@ -0,0 +1,268 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cove/oview/pkg/text2table"
+)
+
+func init() {
+	Register("listen", newListenSource)
+}
+
+// listenSource listens on a socket for the tables other programs send it,
+// each in a datagram, or one after another on a connection separated by
+// blank lines.
+type listenSource struct {
+	sock *socket
+	cfg  Config
+
+	mu     sync.Mutex
+	warned bool
+}
+
+// newListenSource listens on the address in a spec like
+// "listen:tcp://:9000", "listen:udp://127.0.0.1:9000" or
+// "listen:unix:///tmp/oview.sock", where a bare address is TCP.
+func newListenSource(spec Spec, cfg Config) (Source, error) {
+	if spec.Arg == "" {
+		return nil, fmt.Errorf("listen: needs an address like tcp://:9000, udp://:9000 or unix:///tmp/oview.sock")
+	}
+	sock, err := listenOn(spec.Arg, "tcp")
+	if err != nil {
+		return nil, err
+	}
+	return &listenSource{sock: sock, cfg: cfg}, nil
+}
+
+func (s *listenSource) Name() string {
+	return s.sock.String()
+}
+
+func (s *listenSource) Run(ctx context.Context, emit func(Frame)) error {
+
+	// the tables come from several connections at once
+	var mu sync.Mutex
+	send := func(frame Frame) {
+		mu.Lock()
+		defer mu.Unlock()
+		emit(frame)
+	}
+	if s.cfg.Tag {
+		m := newTableMerger(s.Name(), s.cfg.Expire, send)
+		go m.expire(ctx, s.cfg.Interval)
+		send = m.add
+	}
+
+	return s.sock.serve(ctx,
+		func(ctx context.Context, conn net.Conn, tag string) error {
+			return s.readFrames(ctx, conn, tag, send)
+		},
+		func(data []byte, tag string) {
+			s.sendTable(data, tag, send)
+		})
+}
+
+// readFrames reads the tables sent on a connection, which end at a blank
+// line, or at the delimiter line if there is one, and when it's closed.
+func (s *listenSource) readFrames(ctx context.Context, conn net.Conn, tag string, send func(Frame)) error {
+	if s.cfg.Delimiter != "" {
+		warn := func(err error) { s.warn(tag, err) }
+		return streamTables(ctx, conn, tag, s.cfg, false, warn, send)
+	}
+
+	var frame bytes.Buffer
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			s.sendTable(frame.Bytes(), tag, send)
+			frame.Reset()
+			continue
+		}
+		frame.Write(scanner.Bytes())
+		frame.WriteByte('\n')
+	}
+	s.sendTable(frame.Bytes(), tag, send)
+	return scanner.Err()
+}
+
+// sendTable sends the table in a frame, if there's one in it.
+func (s *listenSource) sendTable(data []byte, tag string, send func(Frame)) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return
+	}
+	table, err := text2table.ReadTable(bytes.NewReader(data), s.cfg.Options)
+	if err != nil {
+		s.warn(tag, fmt.Errorf("failed to parse a table: %s", err))
+		return
+	}
+	s.warn(tag, nil)
+	if len(table.Columns) > 0 {
+		send(Frame{Table: table, Time: time.Now(), Source: tag})
+	}
+}
+
+// warn shows an error in the tables from a sender in the status, or clears
+// it when nil.
+func (s *listenSource) warn(tag string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil && !s.warned {
+		return
+	}
+	s.warned = err != nil
+	if err != nil {
+		err = fmt.Errorf("%s: %s", tag, err)
+	}
+	s.cfg.warn(s.Name(), err)
+}
+
+// tableMerger shows the tables of several sources as one, with a SOURCE
+// column naming the one each row came from. The table of a source is
+// replaced by the next one it sends, and dropped once it hasn't sent any for
+// the expiry.
+type tableMerger struct {
+	name   string
+	expiry time.Duration
+	emit   func(Frame)
+
+	mu     sync.Mutex
+	order  []string
+	frames map[string]Frame
+}
+
+func newTableMerger(name string, expiry time.Duration, emit func(Frame)) *tableMerger {
+	return &tableMerger{name: name, expiry: expiry, emit: emit, frames: map[string]Frame{}}
+}
+
+// add replaces the table of the source the frame is from and emits them
+// all.
+func (m *tableMerger) add(frame Frame) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.frames[frame.Source]; !ok {
+		m.order = append(m.order, frame.Source)
+	}
+	m.frames[frame.Source] = frame
+	m.drop(frame.Time)
+	m.emit(Frame{Table: m.table(), Time: frame.Time, Source: m.name})
+}
+
+// expire drops the tables of the sources that have gone quiet every
+// interval until ctx is done, emitting the rest when any are.
+func (m *tableMerger) expire(ctx context.Context, interval time.Duration) {
+	if m.expiry <= 0 {
+		return
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.mu.Lock()
+			if m.drop(now) {
+				m.emit(Frame{Table: m.table(), Time: now, Source: m.name})
+			}
+			m.mu.Unlock()
+		}
+	}
+}
+
+// drop drops the tables that are older than the expiry, reporting whether
+// there were any.
+func (m *tableMerger) drop(now time.Time) bool {
+	if m.expiry <= 0 {
+		return false
+	}
+	order := m.order[:0]
+	for _, source := range m.order {
+		if now.Sub(m.frames[source].Time) > m.expiry {
+			delete(m.frames, source)
+			continue
+		}
+		order = append(order, source)
+	}
+	dropped := len(order) < len(m.order)
+	m.order = order
+	return dropped
+}
+
+// table returns the tables of the sources as one in the order the sources
+// were first seen, with the union of their columns by name. The rows are
+// identified by their source and the key columns of the first table.
+func (m *tableMerger) table() *text2table.Table {
+
+	t := &text2table.Table{
+		Columns: []text2table.Column{{Name: "SOURCE", Type: text2table.Categorical}},
+	}
+	index := map[string]int{}
+	columns := map[string][]int{}
+	for _, source := range m.order {
+		table := m.frames[source].Table
+		cols := make([]int, len(table.Columns))
+		for i, c := range table.Columns {
+			j, ok := index[c.Name]
+			if !ok {
+				j = len(t.Columns)
+				index[c.Name] = j
+				t.Columns = append(t.Columns, c)
+			}
+			cols[i] = j
+		}
+		columns[source] = cols
+	}
+
+	var key []int
+	for i, source := range m.order {
+		table := m.frames[source].Table
+		cols := columns[source]
+		if i == 0 && len(table.Key) > 0 {
+			key = []int{0}
+			for _, k := range table.Key {
+				key = append(key, cols[k])
+			}
+		}
+		for _, row := range table.Rows {
+			cells := make([]text2table.Cell, len(t.Columns))
+			for j := range cells {
+				cells[j] = nullCell()
+			}
+			cells[0] = textCell(source)
+			for j, c := range row.Cells {
+				if j < len(cols) {
+					cells[cols[j]] = c
+				}
+			}
+			t.Rows = append(t.Rows, text2table.Row{Cells: cells})
+		}
+	}
+	t.SetKey(key)
+	return t
+}
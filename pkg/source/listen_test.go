@@ -0,0 +1,164 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cove/oview/pkg/text2table"
+)
+
+func TestListenSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oview")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the rows of the frames emitted after each message
+	type message struct {
+		data   string
+		frames [][][]string
+	}
+	tests := []struct {
+		name string
+		spec string
+		tag  bool
+		// messages sent, each on a connection of its own or in a datagram
+		messages []message
+	}{
+		{
+			name: "tcp frames on one connection",
+			spec: "listen:tcp://127.0.0.1:0",
+			messages: []message{{
+				data: "host,load\nweb1,0.5\nweb2,1.5\n\nhost,load\nweb1,0.7\nweb2,1.1\n",
+				frames: [][][]string{
+					{{"web1", "0.5"}, {"web2", "1.5"}},
+					{{"web1", "0.7"}, {"web2", "1.1"}},
+				},
+			}},
+		},
+		{
+			name: "udp datagrams",
+			spec: "listen:udp://127.0.0.1:0",
+			messages: []message{
+				{
+					data:   `[{"host": "web1", "load": 0.5}, {"host": "web2", "load": 1.5}]`,
+					frames: [][][]string{{{"web1", "0.5"}, {"web2", "1.5"}}},
+				},
+				{
+					data:   "{\"host\": \"web1\", \"load\": 0.7}\n{\"host\": \"web2\", \"load\": 1.1}\n",
+					frames: [][][]string{{{"web1", "0.7"}, {"web2", "1.1"}}},
+				},
+			},
+		},
+		{
+			name: "tagged unix connections",
+			spec: "listen:unix://" + filepath.Join(dir, "oview.sock"),
+			tag:  true,
+			messages: []message{
+				{
+					data:   "host,load\nweb1,0.5\n",
+					frames: [][][]string{{{"#1", "web1", "0.5"}}},
+				},
+				{
+					data:   "host,load,queue\ndb1,2.5,7\n",
+					frames: [][][]string{{{"#1", "web1", "0.5", ""}, {"#2", "db1", "2.5", "7"}}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := New(tt.spec, Config{Tag: tt.tag})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			frames := make(chan Frame, 10)
+			done := make(chan error, 1)
+			go func() {
+				done <- src.Run(ctx, func(frame Frame) {
+					frames <- frame
+				})
+			}()
+
+			addr := src.(*listenSource).sock.addr()
+			for i, msg := range tt.messages {
+				conn, err := net.Dial(addr.Network(), addr.String())
+				if err != nil {
+					t.Fatal(err)
+				}
+				conn.Write([]byte(msg.data))
+				conn.Close()
+
+				for j, want := range msg.frames {
+					select {
+					case frame := <-frames:
+						if rows := cellTexts(frame.Table); !reflect.DeepEqual(rows, want) {
+							t.Errorf("message %d frame %d rows = %q, want %q", i, j, rows, want)
+						}
+					case <-ctx.Done():
+						t.Fatalf("message %d got %d frames, want %d", i, j, len(msg.frames))
+					}
+				}
+			}
+
+			cancel()
+			if err := <-done; err != nil {
+				t.Errorf("Run() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestTableMerger(t *testing.T) {
+	var got []*text2table.Table
+	m := newTableMerger("listen", time.Minute, func(frame Frame) {
+		got = append(got, frame.Table)
+	})
+
+	start := time.Now()
+	web := text2table.FromRows([]string{"ID", "LOAD"}, [][]string{{"a", "1"}, {"b", "2"}})
+	db := text2table.FromRows([]string{"ID", "LOAD"}, [][]string{{"a", "3"}})
+	m.add(Frame{Table: web, Time: start, Source: "web"})
+	m.add(Frame{Table: db, Time: start.Add(30 * time.Second), Source: "db"})
+
+	// rows with the same id from different sources are apart
+	table := got[1]
+	if table.Key == nil || table.Rows[0].ID == table.Rows[2].ID {
+		t.Errorf("merged rows ids = %q, %q, want them apart", table.Rows[0].ID, table.Rows[2].ID)
+	}
+	if want := [][]string{{"web", "a", "1"}, {"web", "b", "2"}, {"db", "a", "3"}}; !reflect.DeepEqual(cellTexts(table), want) {
+		t.Errorf("merged rows = %q, want %q", cellTexts(table), want)
+	}
+
+	// web went quiet
+	m.mu.Lock()
+	dropped := m.drop(start.Add(61 * time.Second))
+	table = m.table()
+	m.mu.Unlock()
+	if want := [][]string{{"db", "a", "3"}}; !dropped || !reflect.DeepEqual(cellTexts(table), want) {
+		t.Errorf("rows after the expiry = %q, want %q", cellTexts(table), want)
+	}
+}
@@ -0,0 +1,360 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cove/oview/pkg/text2table"
+)
+
+func init() {
+	Register("logs", newLogSource)
+}
+
+// DefaultSpan is how long the lines of a log are counted for by default.
+const DefaultSpan = time.Minute
+
+// logSource counts the lines of a log matching a regexp, like the requests
+// in an access log, by the values of some of its named groups over a span
+// of time, summing others like the bytes sent. The table of the counts is
+// emitted every interval.
+type logSource struct {
+	// path of the file to tail, or the reader of stdin
+	path  string
+	stdin io.Reader
+	re    *regexp.Regexp
+	key   []string
+	sum   []string
+	span  time.Duration
+	cfg   Config
+}
+
+// newLogSource tails the file in a spec like "logs:/var/log/access.log", or
+// stdin for "logs:" or "logs:-", counting the lines matching the regexp in
+// the options by the key groups, or by all the groups that aren't summed.
+// The regexp and key that pick the columns and rows of the tables of other
+// sources pick them here too, as the key groups are the columns that
+// identify a row of counts.
+func newLogSource(spec Spec, cfg Config) (Source, error) {
+
+	if cfg.Options.Regexp == "" {
+		return nil, errors.New(`logs: needs a regexp with named groups like (?P<status>\d+) to count lines by`)
+	}
+	re, err := regexp.Compile(cfg.Options.Regexp)
+	if err != nil {
+		return nil, err
+	}
+	var groups []string
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			groups = append(groups, name)
+		}
+	}
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("regexp %q has no named groups like (?P<name>...)", cfg.Options.Regexp)
+	}
+
+	s := &logSource{path: spec.Arg, re: re, span: cfg.Span, cfg: cfg}
+	if s.span <= 0 {
+		s.span = DefaultSpan
+	}
+	if s.path == "-" || s.path == "" {
+		s.path, s.stdin = "", os.Stdin
+	}
+
+	// groups are named, or numbered from 1 like columns
+	group := func(name string) (string, error) {
+		name = strings.TrimSpace(name)
+		for _, g := range groups {
+			if strings.EqualFold(g, name) {
+				return g, nil
+			}
+		}
+		if n, err := strconv.Atoi(name); err == nil && n >= 1 && n <= len(groups) {
+			return groups[n-1], nil
+		}
+		return "", fmt.Errorf("regexp %q has no group %q", cfg.Options.Regexp, name)
+	}
+	summed := map[string]bool{}
+	for _, name := range cfg.Sum {
+		g, err := group(name)
+		if err != nil {
+			return nil, err
+		}
+		s.sum = append(s.sum, g)
+		summed[g] = true
+	}
+	for _, name := range cfg.Options.Key {
+		g, err := group(name)
+		if err != nil {
+			return nil, err
+		}
+		s.key = append(s.key, g)
+	}
+	if len(cfg.Options.Key) == 0 {
+		for _, g := range groups {
+			if !summed[g] {
+				s.key = append(s.key, g)
+			}
+		}
+	}
+	return s, nil
+}
+
+func (s *logSource) Name() string {
+	if s.path == "" {
+		return "logs:stdin"
+	}
+	return "logs:" + s.path
+}
+
+func (s *logSource) Run(ctx context.Context, emit func(Frame)) error {
+
+	counts := newLogCounter(s.key, s.sum, s.span, s.cfg.Interval, time.Now())
+	var mu sync.Mutex
+	add := func(lines []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		now := time.Now()
+		for _, line := range lines {
+			if m := s.re.FindStringSubmatch(line); m != nil {
+				counts.add(s.values(m), now)
+			}
+		}
+	}
+
+	// stdin is read as it comes, while files are tailed every interval
+	read := func() error { return nil }
+	var failed chan error
+	if s.stdin != nil {
+		failed = make(chan error, 1)
+		go func() {
+			scanner := bufio.NewScanner(s.stdin)
+			scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+			for scanner.Scan() {
+				add([]string{scanner.Text()})
+			}
+			failed <- scanner.Err()
+		}()
+	} else {
+		tail := &tailer{path: s.path, fromEnd: true}
+		defer tail.close()
+		read = func() error {
+			lines, err := tail.next()
+			add(lines)
+			return err
+		}
+	}
+
+	s.cfg.supervisor(s.Name()).Run(ctx, func(ctx context.Context, alive func()) error {
+		select {
+		case err := <-failed:
+			if err != nil {
+				return err
+			}
+		default:
+		}
+		if err := read(); err != nil {
+			return err
+		}
+		mu.Lock()
+		table := counts.table(time.Now())
+		mu.Unlock()
+		emit(Frame{Table: table, Time: time.Now(), Source: s.Name()})
+		return nil
+	})
+	return nil
+}
+
+// values returns the values of the named groups in a match.
+func (s *logSource) values(m []string) map[string]string {
+	values := map[string]string{}
+	for i, name := range s.re.SubexpNames() {
+		if name != "" {
+			values[name] = m[i]
+		}
+	}
+	return values
+}
+
+// logCounter counts the lines of a log by the values of the key groups in
+// them, and sums the values of the others, over a span of time. The lines
+// are counted in buckets of a second so the counts slide smoothly.
+type logCounter struct {
+	key  []string
+	sum  []string
+	span time.Duration
+	// least is the shortest time the rates are over, so the lines counted
+	// right after the start don't make for a burst
+	least time.Duration
+	start time.Time
+
+	groups map[string]*logGroup
+	order  []string
+	units  []text2table.Unit
+}
+
+// logGroup is the lines with the same values of the key groups.
+type logGroup struct {
+	values  []string
+	buckets []logBucket
+}
+
+// logBucket is the lines counted in a second, with the sum of each summed
+// group and how many of them were numbers.
+type logBucket struct {
+	second  int64
+	count   float64
+	sums    []float64
+	numbers []float64
+}
+
+// newLogCounter returns a counter over the span, with rates over at least
+// the interval the counts are shown every.
+func newLogCounter(key, sum []string, span, interval time.Duration, now time.Time) *logCounter {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	if interval > span {
+		interval = span
+	}
+	return &logCounter{key: key, sum: sum, span: span, least: interval, start: now, groups: map[string]*logGroup{}, units: make([]text2table.Unit, len(sum))}
+}
+
+// add counts a line with the values of its groups.
+func (c *logCounter) add(values map[string]string, now time.Time) {
+
+	key := make([]string, len(c.key))
+	for i, name := range c.key {
+		key[i] = values[name]
+	}
+	id := strings.Join(key, "\x00")
+	g, ok := c.groups[id]
+	if !ok {
+		g = &logGroup{values: key}
+		c.groups[id] = g
+		c.order = append(c.order, id)
+	}
+
+	second := now.Unix()
+	if n := len(g.buckets); n == 0 || g.buckets[n-1].second != second {
+		g.buckets = append(g.buckets, logBucket{second: second, sums: make([]float64, len(c.sum)), numbers: make([]float64, len(c.sum))})
+	}
+	b := &g.buckets[len(g.buckets)-1]
+	b.count++
+	for i, name := range c.sum {
+		v, err := text2table.ParseValue(values[name])
+		if err != nil {
+			continue
+		}
+		if c.units[i] == text2table.UnitNone {
+			c.units[i] = v.Unit
+		}
+		b.sums[i] += v.Number
+		b.numbers[i]++
+	}
+}
+
+// table returns the counts of the lines in the span before now, dropping
+// the groups without any. It has a column for each key group, the count of
+// lines and their rate per second, and the sum and average of each summed
+// group.
+func (c *logCounter) table(now time.Time) *text2table.Table {
+
+	var columns []text2table.Column
+	var key []int
+	for i, name := range c.key {
+		columns = append(columns, text2table.Column{Name: name, Type: text2table.Categorical})
+		key = append(key, i)
+	}
+	columns = append(columns,
+		text2table.Column{Name: "count", Type: text2table.Numeric},
+		text2table.Column{Name: "count/s", Type: text2table.Numeric})
+	for i, name := range c.sum {
+		columns = append(columns,
+			text2table.Column{Name: name + " sum", Type: text2table.Numeric, Unit: c.units[i]},
+			text2table.Column{Name: name + " avg", Type: text2table.Numeric, Unit: c.units[i]})
+	}
+
+	// the rate is over the time counted until there's a whole span, but
+	// no less than an interval
+	oldest := now.Add(-c.span).Unix()
+	seconds := c.span.Seconds()
+	if since := now.Sub(c.start); since < c.span {
+		if since < c.least {
+			since = c.least
+		}
+		seconds = since.Seconds()
+	}
+
+	b := newTableBuilder(columns...)
+	order := c.order[:0]
+	for _, id := range c.order {
+		g := c.groups[id]
+		i := 0
+		for i < len(g.buckets) && g.buckets[i].second <= oldest {
+			i++
+		}
+		g.buckets = g.buckets[i:]
+		if len(g.buckets) == 0 {
+			delete(c.groups, id)
+			continue
+		}
+		order = append(order, id)
+
+		var count float64
+		sums := make([]float64, len(c.sum))
+		numbers := make([]float64, len(c.sum))
+		for _, bucket := range g.buckets {
+			count += bucket.count
+			for j := range c.sum {
+				sums[j] += bucket.sums[j]
+				numbers[j] += bucket.numbers[j]
+			}
+		}
+
+		var cells []text2table.Cell
+		for _, v := range g.values {
+			cells = append(cells, textCell(v))
+		}
+		rate := nullCell()
+		if seconds > 0 {
+			rate = numberCell(count/seconds, text2table.UnitNone)
+		}
+		cells = append(cells, numberCell(count, text2table.UnitNone), rate)
+		for j := range c.sum {
+			avg := nullCell()
+			if numbers[j] > 0 {
+				avg = numberCell(sums[j]/numbers[j], c.units[j])
+			}
+			cells = append(cells, numberCell(sums[j], c.units[j]), avg)
+		}
+		b.add(cells...)
+	}
+	c.order = order
+
+	return b.table(key...)
+}
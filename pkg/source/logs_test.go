@@ -0,0 +1,220 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cove/oview/pkg/text2table"
+)
+
+// accessLog picks the fields out of the lines of an access log.
+const accessLog = `^(?P<ip>\S+) \S+ \S+ \[[^\]]+\] "(?P<method>\S+) (?P<path>\S+)[^"]*" (?P<status>\d+) (?P<bytes>\S+)`
+
+func TestNewLogSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		key     []string
+		sum     []string
+		wantErr bool
+	}{
+		{
+			name: "key and sum",
+			cfg:  Config{Options: text2table.Options{Regexp: accessLog, Key: []string{"path", "4"}}, Sum: []string{"bytes"}},
+			key:  []string{"path", "status"},
+			sum:  []string{"bytes"},
+		},
+		{
+			name: "every group but the sums",
+			cfg:  Config{Options: text2table.Options{Regexp: accessLog}, Sum: []string{"BYTES"}},
+			key:  []string{"ip", "method", "path", "status"},
+			sum:  []string{"bytes"},
+		},
+		{
+			name:    "no regexp",
+			wantErr: true,
+		},
+		{
+			name:    "no groups",
+			cfg:     Config{Options: text2table.Options{Regexp: `\d+`}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown group",
+			cfg:     Config{Options: text2table.Options{Regexp: accessLog}, Sum: []string{"latency"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := New("logs:-", tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			s := src.(*logSource)
+			if !reflect.DeepEqual(s.key, tt.key) || !reflect.DeepEqual(s.sum, tt.sum) {
+				t.Errorf("New() key = %q, sum = %q, want %q and %q", s.key, s.sum, tt.key, tt.sum)
+			}
+		})
+	}
+}
+
+func TestLogCounter(t *testing.T) {
+	src, err := New("logs:", Config{
+		Options: text2table.Options{Regexp: accessLog, Key: []string{"path", "status"}},
+		Sum:     []string{"bytes"},
+		Span:    10 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := src.(*logSource)
+
+	data, err := ioutil.ReadFile("testdata/access.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Unix(1000, 0)
+	c := newLogCounter(s.key, s.sum, s.span, 2*time.Second, start)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		// the seconds are taken from the time in the line
+		if m := s.re.FindStringSubmatch(line); m != nil {
+			at := time.Duration(line[strings.Index(line, "]")-7]-'0') * time.Second
+			c.add(s.values(m), start.Add(at))
+		}
+	}
+
+	header := []string{"path", "status", "count", "count/s", "bytes sum", "bytes avg"}
+	tests := []struct {
+		at   time.Duration
+		rows [][]string
+	}{
+		{
+			// over the 5 seconds since the start
+			at: 5 * time.Second,
+			rows: [][]string{
+				{"/api/users", "200", "3", "0.6", "3.0 KiB", "1.0 KiB"},
+				{"/api/orders", "201", "1", "0.2", "128 B", "128 B"},
+				{"/api/users", "500", "1", "0.2", "0 B", "0 B"},
+				{"/static/app.js", "304", "1", "0.2", "0 B", ""},
+			},
+		},
+		{
+			// the lines of the first 2 seconds are out of the span
+			at: 12 * time.Second,
+			rows: [][]string{
+				{"/api/users", "200", "1", "0.1", "1.0 KiB", "1.0 KiB"},
+				{"/static/app.js", "304", "1", "0.1", "0 B", ""},
+			},
+		},
+		{
+			at: 20 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		table := c.table(start.Add(tt.at))
+		if got := table.Header(); !reflect.DeepEqual(got, header) {
+			t.Errorf("at %s: header = %q, want %q", tt.at, got, header)
+		}
+		if got := cellTexts(table); !reflect.DeepEqual(got, tt.rows) {
+			t.Errorf("at %s: rows =\n%q\nwant\n%q", tt.at, got, tt.rows)
+		}
+	}
+}
+
+func TestLogCounterStart(t *testing.T) {
+	start := time.Unix(1000, 0)
+	c := newLogCounter([]string{"status"}, nil, time.Minute, 5*time.Second, start)
+	c.add(map[string]string{"status": "200"}, start)
+
+	// a line right after the start is a rate over the interval, not a burst
+	want := [][]string{{"200", "1", "0.2"}}
+	if got := cellTexts(c.table(start.Add(100 * time.Millisecond))); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %q, want %q", got, want)
+	}
+}
+
+func TestLogSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oview")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "access.log")
+
+	// what's in the log before it's tailed isn't counted
+	data, err := ioutil.ReadFile("testdata/access.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := New("logs:"+path, Config{
+		Options:  text2table.Options{Regexp: accessLog, Key: []string{"status"}},
+		Interval: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	frames := make(chan Frame)
+	go src.Run(ctx, func(frame Frame) {
+		select {
+		case frames <- frame:
+		case <-ctx.Done():
+		}
+	})
+
+	// the file is opened by the time the first table is emitted
+	select {
+	case <-frames:
+	case <-ctx.Done():
+		t.Fatal("no table emitted")
+	}
+	fd, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd.Write(data[:strings.Index(string(data), "\n")+1])
+	fd.Close()
+
+	want := [][]string{{"200", "1"}}
+	var got [][]string
+	for !reflect.DeepEqual(got, want) {
+		select {
+		case frame := <-frames:
+			got = nil
+			for _, row := range cellTexts(frame.Table) {
+				got = append(got, row[:2])
+			}
+		case <-ctx.Done():
+			t.Fatalf("rows = %q, want %q", got, want)
+		}
+	}
+}
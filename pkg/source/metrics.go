@@ -0,0 +1,366 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cove/oview/pkg/text2table"
+)
+
+// metricKind is how the values of a metric are aggregated.
+type metricKind int
+
+const (
+	// gauges show their last value
+	gauge metricKind = iota
+	// counters show the rate they were counted at
+	counter
+	// timers show percentiles of the values in each interval
+	timer
+	// sets show how many distinct values there were in each interval
+	set
+)
+
+// metricTag is a tag of a metric, like host=web1.
+type metricTag struct {
+	key, value string
+}
+
+// point is a value of a field of a metric, received from a program.
+type point struct {
+	// name of the metric, the measurement in InfluxDB
+	name string
+	// tags of the metric sorted by key, which with the name make a series
+	tags  []metricTag
+	field string
+	kind  metricKind
+	value float64
+	// text of a set member, or of a gauge that isn't a number
+	text string
+	// unit of the value
+	unit text2table.Unit
+	// rate a counter or timer was sampled at, from 0 to 1
+	rate float64
+	// delta is set for a change to a gauge rather than a value
+	delta bool
+}
+
+// metricsSource receives metrics from programs in a line protocol like
+// StatsD's, showing a table of them aggregated every interval.
+type metricsSource struct {
+	scheme string
+	sock   *socket
+	parse  func(line string) ([]point, error)
+	cfg    Config
+}
+
+// newMetricsSource listens on the address in the spec, or the default one
+// for a bare scheme, for metrics parsed by parse.
+func newMetricsSource(spec Spec, cfg Config, address string, parse func(string) ([]point, error)) (Source, error) {
+	if spec.Arg != "" {
+		address = spec.Arg
+	}
+	sock, err := listenOn(address, "udp")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", spec.Scheme, err)
+	}
+	return &metricsSource{scheme: spec.Scheme, sock: sock, parse: parse, cfg: cfg}, nil
+}
+
+func (s *metricsSource) Name() string {
+	return s.scheme + ":" + s.sock.String()
+}
+
+func (s *metricsSource) Run(ctx context.Context, emit func(Frame)) error {
+
+	agg := newAggregator(s.cfg.Expire, time.Now())
+	var mu sync.Mutex
+	warned := false
+	add := func(data []byte, tag string) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line == "" || line[0] == '#' {
+				continue
+			}
+			points, err := s.parse(line)
+			if err != nil {
+				s.cfg.warn(s.Name(), fmt.Errorf("%s: %s", tag, err))
+				warned = true
+				continue
+			}
+			if warned {
+				s.cfg.warn(s.Name(), nil)
+				warned = false
+			}
+			for _, p := range points {
+				agg.add(p, time.Now())
+			}
+		}
+	}
+
+	// flush what's been received every interval
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		interval := s.cfg.Interval
+		if interval <= 0 {
+			interval = time.Second
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				mu.Lock()
+				table := agg.flush(now)
+				mu.Unlock()
+				if table != nil {
+					emit(Frame{Table: table, Time: now, Source: s.Name()})
+				}
+			}
+		}
+	}()
+
+	return s.sock.serve(ctx,
+		func(ctx context.Context, conn net.Conn, tag string) error {
+			scanner := bufio.NewScanner(conn)
+			scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+			for scanner.Scan() {
+				add(scanner.Bytes(), tag)
+			}
+			return scanner.Err()
+		},
+		func(data []byte, tag string) {
+			add(bytes.TrimSpace(data), tag)
+		})
+}
+
+// aggregator aggregates the points of each series, a metric and its tags,
+// over an interval into a table with a row for each field of a series. A
+// series is dropped once it hasn't been received for the expiry, unless
+// it's zero.
+type aggregator struct {
+	expiry time.Duration
+	last   time.Time
+	series map[string]*series
+	order  []string
+}
+
+// series is a metric and its tags, with the fields received for it.
+type series struct {
+	name   string
+	tags   string
+	fields map[string]*fieldValues
+	order  []string
+	seen   time.Time
+}
+
+// fieldValues are the values of a field received in an interval, or the
+// last one for gauges.
+type fieldValues struct {
+	kind    metricKind
+	unit    text2table.Unit
+	value   float64
+	text    string
+	count   float64
+	samples []float64
+	members map[string]bool
+}
+
+// metricColumns are the columns of the table of metrics, the same whatever
+// is received so they don't move as more metrics are seen. The tags of a
+// series are in one column, and each field has a row with the columns of
+// its kind filled in.
+var metricColumns = []text2table.Column{
+	{Name: "METRIC", Type: text2table.Categorical},
+	{Name: "TAGS", Type: text2table.Categorical},
+	{Name: "VALUE", Type: text2table.Numeric},
+	{Name: "RATE/s", Type: text2table.Numeric},
+	{Name: "P50", Type: text2table.Numeric},
+	{Name: "P90", Type: text2table.Numeric},
+	{Name: "P99", Type: text2table.Numeric},
+	{Name: "MAX", Type: text2table.Numeric},
+	{Name: "UNIQUE", Type: text2table.Numeric},
+}
+
+// percentiles shown for timers, in the columns after RATE/s.
+var percentiles = []float64{50, 90, 99}
+
+func newAggregator(expiry time.Duration, now time.Time) *aggregator {
+	return &aggregator{expiry: expiry, last: now, series: map[string]*series{}}
+}
+
+// add adds a point to its series.
+func (a *aggregator) add(p point, now time.Time) {
+
+	tags := make([]string, len(p.tags))
+	for i, t := range p.tags {
+		tags[i] = t.key + "=" + t.value
+	}
+	key := strings.Join(append([]string{p.name}, tags...), ",")
+	s, ok := a.series[key]
+	if !ok {
+		s = &series{name: p.name, tags: strings.Join(tags, ","), fields: map[string]*fieldValues{}}
+		a.series[key] = s
+		a.order = append(a.order, key)
+	}
+	s.seen = now
+
+	// a metric sent as another kind starts over
+	f, ok := s.fields[p.field]
+	if !ok {
+		s.order = append(s.order, p.field)
+	}
+	if !ok || f.kind != p.kind || f.unit != p.unit {
+		f = &fieldValues{kind: p.kind, unit: p.unit, members: map[string]bool{}}
+		s.fields[p.field] = f
+	}
+
+	rate := p.rate
+	if rate <= 0 || rate > 1 {
+		rate = 1
+	}
+	switch p.kind {
+	case gauge:
+		if p.delta {
+			f.value += p.value
+		} else {
+			f.value, f.text = p.value, p.text
+		}
+	case counter:
+		f.count += p.value / rate
+	case timer:
+		f.samples = append(f.samples, p.value)
+		f.count += 1 / rate
+	case set:
+		f.members[p.text] = true
+	}
+}
+
+// flush returns the table of the series aggregated since the last flush, or
+// nil if there are none, and starts the next interval.
+func (a *aggregator) flush(now time.Time) *text2table.Table {
+
+	elapsed := now.Sub(a.last).Seconds()
+	a.last = now
+
+	order := a.order[:0]
+	for _, key := range a.order {
+		if a.expiry > 0 && now.Sub(a.series[key].seen) > a.expiry {
+			delete(a.series, key)
+			continue
+		}
+		order = append(order, key)
+	}
+	a.order = order
+	if len(a.order) == 0 {
+		return nil
+	}
+
+	b := newTableBuilder(metricColumns...)
+	for _, key := range a.order {
+		s := a.series[key]
+		for _, field := range s.order {
+			f := s.fields[field]
+
+			// the field of a statsd metric is its only one, others are
+			// shown after its name like "cpu.usage"
+			name := s.name
+			if field != "value" {
+				name += "." + field
+			}
+			b.add(append([]text2table.Cell{textCell(name), textCell(s.tags)}, f.cells(elapsed)...)...)
+			f.reset()
+		}
+	}
+
+	// a row is the field of a metric and its tags
+	return b.table(0, 1)
+}
+
+// cells returns the cells of a field aggregated over the elapsed seconds,
+// for the columns after TAGS. Gauges have their value, counters their rate,
+// timers the rate they were timed at and percentiles of their values, and
+// sets how many members they had.
+func (f *fieldValues) cells(elapsed float64) []text2table.Cell {
+
+	cells := make([]text2table.Cell, len(metricColumns)-2)
+	for i := range cells {
+		cells[i] = nullCell()
+	}
+	// the indexes of the columns after TAGS
+	const value, rate, p50, highest, unique = 0, 1, 2, 5, 6
+
+	if (f.kind == counter || f.kind == timer) && elapsed > 0 {
+		cells[rate] = numberCell(f.count/elapsed, text2table.UnitNone)
+	}
+
+	switch f.kind {
+	case gauge:
+		if f.text != "" {
+			cells[value] = textCell(f.text)
+		} else {
+			cells[value] = numberCell(f.value, f.unit)
+		}
+
+	case timer:
+		if len(f.samples) == 0 {
+			break
+		}
+		sort.Float64s(f.samples)
+		for i, p := range percentiles {
+			cells[p50+i] = numberCell(percentile(f.samples, p), f.unit)
+		}
+		cells[highest] = numberCell(percentile(f.samples, 100), f.unit)
+
+	case set:
+		cells[unique] = numberCell(float64(len(f.members)), text2table.UnitNone)
+	}
+
+	return cells
+}
+
+// reset starts the next interval of a field, where gauges keep their value.
+func (f *fieldValues) reset() {
+	f.count = 0
+	f.samples = nil
+	f.members = map[string]bool{}
+}
+
+// percentile returns the pth percentile of the sorted values by the nearest
+// rank, or 0 if there are none.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
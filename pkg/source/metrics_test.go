@@ -0,0 +1,148 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAggregator(t *testing.T) {
+	start := time.Now()
+	a := newAggregator(time.Minute, start)
+	add := func(lines string, at time.Duration) {
+		for _, line := range strings.Split(lines, "\n") {
+			points, err := parseStatsd(line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range points {
+				a.add(p, start.Add(at))
+			}
+		}
+	}
+
+	// counters as rates, gauges as the last value and timers as
+	// percentiles, in seconds
+	add(`api.requests,host=web1:4|c
+api.requests,host=web1:1|c|@0.5
+api.requests,host=web2:2|c
+queue.depth:10|g
+queue.depth:+2|g
+api.latency,host=web1:10|ms
+api.latency,host=web1:30|ms
+api.latency,host=web1:20|ms
+users:alice|s
+users:bob|s
+users:alice|s`, time.Second)
+	table := a.flush(start.Add(2 * time.Second))
+
+	header := []string{"METRIC", "TAGS", "VALUE", "RATE/s", "P50", "P90", "P99", "MAX", "UNIQUE"}
+	if got := table.Header(); !reflect.DeepEqual(got, header) {
+		t.Errorf("header = %q, want %q", got, header)
+	}
+	want := [][]string{
+		{"api.requests", "host=web1", "", "3", "", "", "", "", ""},
+		{"api.requests", "host=web2", "", "1", "", "", "", "", ""},
+		{"queue.depth", "", "12", "", "", "", "", "", ""},
+		{"api.latency", "host=web1", "", "1.5", "20ms", "30ms", "30ms", "30ms", ""},
+		{"users", "", "", "", "", "", "", "", "2"},
+	}
+	if got := cellTexts(table); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows =\n%q\nwant\n%q", got, want)
+	}
+	if id := table.Rows[1].ID; id != "api.requests/host=web2" {
+		t.Errorf("row id = %q, want the metric and its tags", id)
+	}
+
+	// the next interval starts over but for gauges, and web2 goes quiet
+	add("api.requests,host=web1:10|c", 30*time.Second)
+	table = a.flush(start.Add(12 * time.Second))
+	want = [][]string{
+		{"api.requests", "host=web1", "", "1", "", "", "", "", ""},
+		{"api.requests", "host=web2", "", "0", "", "", "", "", ""},
+		{"queue.depth", "", "12", "", "", "", "", "", ""},
+		{"api.latency", "host=web1", "", "0", "", "", "", "", ""},
+		{"users", "", "", "", "", "", "", "", "0"},
+	}
+	if got := cellTexts(table); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows =\n%q\nwant\n%q", got, want)
+	}
+
+	table = a.flush(start.Add(62 * time.Second))
+	want = [][]string{{"api.requests", "host=web1", "", "0", "", "", "", "", ""}}
+	if got := cellTexts(table); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows after the expiry =\n%q\nwant\n%q", got, want)
+	}
+	if table = a.flush(start.Add(100 * time.Second)); table != nil {
+		t.Errorf("table after every series expired = %q, want none", cellTexts(table))
+	}
+}
+
+func TestMetricsSource(t *testing.T) {
+	tests := []struct {
+		spec  string
+		lines string
+		want  [][]string
+	}{
+		{
+			spec:  "statsd:udp://127.0.0.1:0",
+			lines: "queue.depth,host=web1:7|g\nqueue.depth,host=web2:3|g\n",
+			want: [][]string{
+				{"queue.depth", "host=web1", "7", "", "", "", "", "", ""},
+				{"queue.depth", "host=web2", "3", "", "", "", "", "", ""},
+			},
+		},
+		{
+			spec:  "influx:tcp://127.0.0.1:0",
+			lines: "# a comment\nmem,host=web1 used=12i\n\nmem,host=web2 used=3i\n",
+			want: [][]string{
+				{"mem.used", "host=web1", "12", "", "", "", "", "", ""},
+				{"mem.used", "host=web2", "3", "", "", "", "", "", ""},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			src, err := New(tt.spec, Config{Interval: 50 * time.Millisecond})
+			if err != nil {
+				t.Fatal(err)
+			}
+			addr := src.(*metricsSource).sock.addr()
+			conn, err := net.Dial(addr.Network(), addr.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn.Write([]byte(tt.lines))
+			conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			var got [][]string
+			src.Run(ctx, func(frame Frame) {
+				if got = cellTexts(frame.Table); len(got) == len(tt.want) {
+					cancel()
+				}
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rows = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
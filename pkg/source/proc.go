@@ -0,0 +1,290 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cove/oview/pkg/text2table"
+)
+
+func init() {
+	Register("proc", newProcSource)
+}
+
+// procRoot is where proc is mounted.
+const procRoot = "/proc"
+
+// clockTicks is USER_HZ, the unit of the times in /proc/<pid>/stat, which
+// is 100 on every platform Linux runs on.
+const clockTicks = 100
+
+// newProcSource makes a source of the processes in /proc, or in the proc
+// mounted where the spec says, like "proc:/host/proc" in a container.
+func newProcSource(spec Spec, cfg Config) (Source, error) {
+	root := spec.Arg
+	if root == "" {
+		root = procRoot
+	}
+	c := newProcCollector(root)
+	return &collectorSource{name: spec.String(), collect: c.collect, cfg: cfg}, nil
+}
+
+// procCollector reads a table of the processes from proc, like `ps aux`
+// but without forking ps and parsing its output.
+type procCollector struct {
+	root     string
+	pageSize uint64
+	// userName returns the name of the user with the uid
+	userName func(uid string) string
+
+	users map[string]string
+	// last are the CPU times of the processes in the last table, and
+	// uptime the uptime it was read at, to work out the CPU usage in the
+	// time since
+	last   map[int]procTimes
+	uptime float64
+}
+
+// procTimes are the CPU ticks a process used by a time, and when it
+// started so a reused pid isn't taken for the same process.
+type procTimes struct {
+	ticks uint64
+	start uint64
+}
+
+// process is what's read about a process from proc.
+type process struct {
+	pid     int
+	ppid    int
+	comm    string
+	state   string
+	uid     string
+	threads int
+	times   procTimes
+	rss     uint64
+	vsz     uint64
+	read    uint64
+	written uint64
+	hasIO   bool
+	cmdline string
+}
+
+func newProcCollector(root string) *procCollector {
+	c := &procCollector{root: root, pageSize: uint64(os.Getpagesize()), users: map[string]string{}}
+	c.userName = c.lookupUser
+	return c
+}
+
+// procColumns are the columns of the table of processes.
+var procColumns = []text2table.Column{
+	{Name: "PID", Type: text2table.Identifier},
+	{Name: "PPID", Type: text2table.Categorical},
+	{Name: "USER", Type: text2table.Categorical},
+	{Name: "STATE", Type: text2table.Categorical},
+	{Name: "%CPU", Type: text2table.Numeric, Unit: text2table.UnitPercent},
+	{Name: "RSS", Type: text2table.Numeric, Unit: text2table.UnitBytes},
+	{Name: "VSZ", Type: text2table.Numeric, Unit: text2table.UnitBytes},
+	{Name: "THREADS", Type: text2table.Numeric},
+	{Name: "READ", Type: text2table.Numeric, Unit: text2table.UnitBytes},
+	{Name: "WRITE", Type: text2table.Numeric, Unit: text2table.UnitBytes},
+	{Name: "COMMAND", Type: text2table.Text},
+}
+
+// collect reads the table of processes. The CPU usage is over the time
+// since the last table, or since a process started if it wasn't in it.
+func (c *procCollector) collect() (*text2table.Table, error) {
+
+	uptime, err := readUptime(c.root)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.Open(c.root)
+	if err != nil {
+		return nil, err
+	}
+	names, err := dir.Readdirnames(-1)
+	dir.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var pids []int
+	for _, name := range names {
+		if pid, err := strconv.Atoi(name); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	sort.Ints(pids)
+
+	b := newTableBuilder(procColumns...)
+	times := make(map[int]procTimes, len(pids))
+	for _, pid := range pids {
+		p, err := c.readProcess(pid)
+		if err != nil {
+			// it exited while being read
+			continue
+		}
+		times[pid] = p.times
+
+		var cpu float64
+		if last, ok := c.last[pid]; ok && last.start == p.times.start && uptime > c.uptime {
+			cpu = float64(p.times.ticks-last.ticks) / clockTicks / (uptime - c.uptime) * 100
+		} else if age := uptime - float64(p.times.start)/clockTicks; age > 0 {
+			cpu = float64(p.times.ticks) / clockTicks / age * 100
+		}
+
+		read, written := nullCell(), nullCell()
+		if p.hasIO {
+			read = numberCell(float64(p.read), text2table.UnitBytes)
+			written = numberCell(float64(p.written), text2table.UnitBytes)
+		}
+		command := p.cmdline
+		if command == "" {
+			// kernel threads have no command line
+			command = "[" + p.comm + "]"
+		}
+
+		b.add(
+			textCell(strconv.Itoa(p.pid)),
+			textCell(strconv.Itoa(p.ppid)),
+			textCell(c.userName(p.uid)),
+			textCell(p.state),
+			numberCell(cpu, text2table.UnitPercent),
+			numberCell(float64(p.rss), text2table.UnitBytes),
+			numberCell(float64(p.vsz), text2table.UnitBytes),
+			numberCell(float64(p.threads), text2table.UnitNone),
+			read,
+			written,
+			textCell(command),
+		)
+	}
+	c.last, c.uptime = times, uptime
+
+	return b.table(0), nil
+}
+
+// readProcess reads a process from its stat, status, statm, io and cmdline
+// files. Only io may be missing, as it's only readable by the owner.
+func (c *procCollector) readProcess(pid int) (*process, error) {
+
+	dir := filepath.Join(c.root, strconv.Itoa(pid))
+	p := &process{pid: pid}
+
+	// the command name in stat is in parens and may have spaces and parens
+	// in it, so the fields after it are found from the last paren
+	stat, err := ioutil.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return nil, err
+	}
+	open, end := bytes.IndexByte(stat, '('), bytes.LastIndexByte(stat, ')')
+	if open < 0 || end < open {
+		return nil, fmt.Errorf("bad stat for pid %d", pid)
+	}
+	p.comm = string(stat[open+1 : end])
+	fields := strings.Fields(string(stat[end+1:]))
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("bad stat for pid %d", pid)
+	}
+	// fields are numbered from 1 in proc(5), the state being the 3rd
+	field := func(n int) uint64 {
+		v, _ := strconv.ParseUint(fields[n-3], 10, 64)
+		return v
+	}
+	p.state = fields[0]
+	p.ppid = int(field(4))
+	p.times.ticks = field(14) + field(15)
+	p.threads = int(field(20))
+	p.times.start = field(22)
+
+	status, err := readKeyValues(filepath.Join(dir, "status"))
+	if err != nil {
+		return nil, err
+	}
+	if uid := strings.Fields(status["Uid"]); len(uid) > 0 {
+		p.uid = uid[0]
+	}
+	if threads, err := strconv.Atoi(status["Threads"]); err == nil {
+		p.threads = threads
+	}
+
+	statm, err := ioutil.ReadFile(filepath.Join(dir, "statm"))
+	if err != nil {
+		return nil, err
+	}
+	if pages := strings.Fields(string(statm)); len(pages) >= 2 {
+		size, _ := strconv.ParseUint(pages[0], 10, 64)
+		resident, _ := strconv.ParseUint(pages[1], 10, 64)
+		p.vsz, p.rss = size*c.pageSize, resident*c.pageSize
+	}
+
+	if io, err := readKeyValues(filepath.Join(dir, "io")); err == nil {
+		p.read, _ = strconv.ParseUint(io["read_bytes"], 10, 64)
+		p.written, _ = strconv.ParseUint(io["write_bytes"], 10, 64)
+		p.hasIO = true
+	}
+
+	cmdline, err := ioutil.ReadFile(filepath.Join(dir, "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+	p.cmdline = strings.TrimSpace(string(bytes.Replace(cmdline, []byte{0}, []byte{' '}, -1)))
+
+	return p, nil
+}
+
+// readKeyValues reads a file of "key: value" lines like
+// /proc/<pid>/status.
+func readKeyValues(path string) (map[string]string, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, ':'); i > 0 {
+			values[line[:i]] = strings.TrimSpace(line[i+1:])
+		}
+	}
+	return values, scanner.Err()
+}
+
+// lookupUser returns the name of the user with the uid, or the uid if it
+// has no name.
+func (c *procCollector) lookupUser(uid string) string {
+	if name, ok := c.users[uid]; ok {
+		return name
+	}
+	name := uid
+	if u, err := user.LookupId(uid); err == nil {
+		name = u.Username
+	}
+	c.users[uid] = name
+	return name
+}
@@ -0,0 +1,81 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProcCollector(t *testing.T) {
+	c := newProcCollector("testdata/proc/t0")
+	c.pageSize = 4096
+	c.userName = func(uid string) string {
+		return map[string]string{"0": "root", "1000": "cove"}[uid]
+	}
+
+	tests := []struct {
+		root string
+		rows [][]string
+	}{
+		{
+			// usage since each process started
+			root: "testdata/proc/t0",
+			rows: [][]string{
+				{"1", "0", "root", "S", "1%", "11.7 MiB", "19.5 MiB", "1", "1.0 MiB", "2.0 MiB", "/sbin/init splash"},
+				{"42", "1", "cove", "R", "5%", "50.0 MiB", "97.7 MiB", "4", "0 B", "4.0 KiB", "python3 my (odd) name.py --port 8080"},
+				{"77", "2", "root", "I", "0%", "0 B", "0 B", "1", "", "", "[kworker/0:1]"},
+			},
+		},
+		{
+			// usage since the last table, and since starting for a new one
+			root: "testdata/proc/t1",
+			rows: [][]string{
+				{"1", "0", "root", "S", "5%", "11.7 MiB", "19.5 MiB", "1", "1.0 MiB", "2.0 MiB", "/sbin/init splash"},
+				{"42", "1", "cove", "R", "50%", "50.8 MiB", "97.7 MiB", "5", "0 B", "8.0 KiB", "python3 my (odd) name.py --port 8080"},
+				{"77", "2", "root", "I", "0%", "0 B", "0 B", "1", "", "", "[kworker/0:1]"},
+				{"99", "42", "cove", "S", "50%", "800.0 KiB", "1.6 MiB", "1", "0 B", "0 B", "sleep 600"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.root, func(t *testing.T) {
+			c.root = tt.root
+			table, err := c.collect()
+			if err != nil {
+				t.Fatalf("collect() error = %v", err)
+			}
+			if got := table.Header(); !reflect.DeepEqual(got, []string{"PID", "PPID", "USER", "STATE", "%CPU", "RSS", "VSZ", "THREADS", "READ", "WRITE", "COMMAND"}) {
+				t.Errorf("collect() header = %q", got)
+			}
+			rows := cellTexts(table)
+			for i, row := range table.Rows {
+				if row.ID != rows[i][0] {
+					t.Errorf("collect() row id = %q, want the pid %q", row.ID, rows[i][0])
+				}
+			}
+			if !reflect.DeepEqual(rows, tt.rows) {
+				t.Errorf("collect() rows =\n%q\nwant\n%q", rows, tt.rows)
+			}
+		})
+	}
+}
+
+func TestProcCollectorMissing(t *testing.T) {
+	c := newProcCollector("testdata/proc/none")
+	if _, err := c.collect(); err == nil {
+		t.Error("collect() error = nil, want one for a missing proc")
+	}
+}
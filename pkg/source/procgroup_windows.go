@@ -0,0 +1,31 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build windows
+// +build windows
+
+package source
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup does nothing as there are no process groups to kill
+// pipelines with.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills the command, but not any children it started.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,172 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxDatagram is the size of the largest datagram that can be received.
+const maxDatagram = 64 * 1024
+
+// socket is a listening socket, for streams or for datagrams.
+type socket struct {
+	network  string
+	listener net.Listener
+	packets  net.PacketConn
+}
+
+// listenOn listens on an address like "tcp://:9000", "udp://127.0.0.1:9000"
+// or "unix:///tmp/oview.sock", on the network given for a bare address.
+func listenOn(address, network string) (*socket, error) {
+	addr := address
+	if i := strings.Index(addr, "://"); i >= 0 {
+		network, addr = strings.ToLower(addr[:i]), addr[i+3:]
+	}
+	if addr == "" {
+		return nil, fmt.Errorf("%q has no address to listen on", address)
+	}
+	if strings.HasPrefix(network, "tcp") || strings.HasPrefix(network, "udp") {
+		if !strings.Contains(addr, ":") {
+			addr = ":" + addr
+		}
+	}
+
+	s := &socket{network: network}
+	var err error
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		s.listener, err = net.Listen(network, addr)
+	case "unix":
+		removeStaleSocket(addr)
+		s.listener, err = net.Listen(network, addr)
+	case "udp", "udp4", "udp6", "unixgram":
+		s.packets, err = net.ListenPacket(network, addr)
+	default:
+		err = fmt.Errorf("can't listen on %s, use tcp, udp or unix", network)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// removeStaleSocket removes a unix socket left behind by a listener that
+// didn't exit cleanly, which no one answers on.
+func removeStaleSocket(path string) {
+	if fi, err := os.Stat(path); err != nil || fi.Mode()&os.ModeSocket == 0 {
+		return
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return
+	}
+	os.Remove(path)
+}
+
+// String returns the address listened on like "udp://127.0.0.1:8125".
+func (s *socket) String() string {
+	return s.network + "://" + s.addr().String()
+}
+
+// addr returns the address listened on, with the port picked for it if
+// none was given.
+func (s *socket) addr() net.Addr {
+	if s.listener != nil {
+		return s.listener.Addr()
+	}
+	return s.packets.LocalAddr()
+}
+
+// serve passes each connection to the socket to conn, or each datagram to
+// packet, until ctx is done and the socket is closed. Both are given a tag
+// telling apart where the data came from, and connections are closed once
+// conn returns.
+func (s *socket) serve(ctx context.Context, conn func(ctx context.Context, c net.Conn, tag string) error, packet func(data []byte, tag string)) error {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		if s.listener != nil {
+			s.listener.Close()
+		} else {
+			s.packets.Close()
+		}
+	}()
+
+	if s.packets != nil {
+		buf := make([]byte, maxDatagram)
+		for {
+			n, from, err := s.packets.ReadFrom(buf)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				if ne, ok := err.(net.Error); ok && ne.Temporary() {
+					continue
+				}
+				return err
+			}
+			tag := "#0"
+			if from != nil && from.String() != "" {
+				tag = from.String()
+			}
+			packet(buf[:n], tag)
+		}
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for n := 1; ; n++ {
+		c, err := s.listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+
+		// unix sockets have no address to tell connections apart
+		tag := c.RemoteAddr().String()
+		if tag == "" || tag == "@" || s.network == "unix" {
+			tag = fmt.Sprintf("#%d", n)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			connCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			go func() {
+				<-connCtx.Done()
+				c.Close()
+			}()
+			if err := conn(connCtx, c, tag); err != nil && ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "Failed to read data from %s: %s\n", tag, err)
+			}
+		}()
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/cove/oview/pkg/text2table"
+)
+
+func init() {
+	Register("sockets", newSystemSource(newSocketCollector))
+}
+
+// inetTables are the files in /proc/net of the internet sockets by protocol.
+var inetTables = []string{"tcp", "tcp6", "udp", "udp6"}
+
+// tcpStates are the names of the states in /proc/net/tcp, from
+// include/net/tcp_states.h. A UDP socket is ESTABLISHED when it's
+// connected and CLOSE otherwise.
+var tcpStates = map[string]string{
+	"01": "ESTABLISHED", "02": "SYN_SENT", "03": "SYN_RECV", "04": "FIN_WAIT1",
+	"05": "FIN_WAIT2", "06": "TIME_WAIT", "07": "CLOSE", "08": "CLOSE_WAIT",
+	"09": "LAST_ACK", "0A": "LISTEN", "0B": "CLOSING", "0C": "NEW_SYN_RECV",
+}
+
+// unixStates are the names of the states in /proc/net/unix, from the
+// socket_state enum in include/uapi/linux/net.h.
+var unixStates = map[string]string{
+	"01": "UNCONNECTED", "02": "CONNECTING", "03": "CONNECTED", "04": "DISCONNECTING",
+}
+
+// unixAcceptCon is the flag of a unix socket that's listening.
+const unixAcceptCon = 0x10000
+
+// socketColumns are the columns of the table of sockets.
+var socketColumns = []text2table.Column{
+	{Name: "PROTO", Type: text2table.Categorical},
+	{Name: "LOCAL", Type: text2table.Text},
+	{Name: "REMOTE", Type: text2table.Text},
+	{Name: "STATE", Type: text2table.Categorical},
+	{Name: "SEND-Q", Type: text2table.Numeric, Unit: text2table.UnitBytes},
+	{Name: "RECV-Q", Type: text2table.Numeric, Unit: text2table.UnitBytes},
+	{Name: "PID", Type: text2table.Categorical},
+	{Name: "COMMAND", Type: text2table.Categorical},
+	{Name: "INODE", Type: text2table.Identifier},
+}
+
+// newSocketCollector returns a collector of the TCP, UDP and unix sockets
+// from /proc/net, like `ss -tuxap`, with the process that owns each one.
+// They're identified by protocol, local and remote address and inode, as
+// one connection is the same from one table to the next, and the inode
+// tells apart the connections to the same unix socket.
+func newSocketCollector(root string) func() (*text2table.Table, error) {
+	return func() (*text2table.Table, error) {
+
+		owners := socketOwners(root)
+		owner := func(inode string) (text2table.Cell, text2table.Cell) {
+			if o, ok := owners[inode]; ok {
+				return textCell(strconv.Itoa(o.pid)), textCell(o.comm)
+			}
+			return nullCell(), nullCell()
+		}
+
+		b := newTableBuilder(socketColumns...)
+		read := 0
+		for _, proto := range inetTables {
+			lines, err := readLines(filepath.Join(root, "net", proto))
+			if os.IsNotExist(err) {
+				// no IPv6 or the module isn't loaded
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			read++
+
+			for _, line := range lines[1:] {
+				// sl local remote st tx_queue:rx_queue tr:when retrnsmt uid
+				// timeout inode
+				fields := strings.Fields(line)
+				if len(fields) < 10 {
+					continue
+				}
+				local, err := parseSocketAddr(fields[1], hostByteOrder)
+				if err != nil {
+					continue
+				}
+				remote, err := parseSocketAddr(fields[2], hostByteOrder)
+				if err != nil {
+					continue
+				}
+				state := tcpStates[fields[3]]
+				if state == "" {
+					state = fields[3]
+				}
+				var sendQ, recvQ uint64
+				if queues := strings.SplitN(fields[4], ":", 2); len(queues) == 2 {
+					sendQ, _ = strconv.ParseUint(queues[0], 16, 64)
+					recvQ, _ = strconv.ParseUint(queues[1], 16, 64)
+				}
+				pid, comm := owner(fields[9])
+
+				b.add(
+					textCell(proto),
+					textCell(local),
+					textCell(remote),
+					textCell(state),
+					numberCell(float64(sendQ), text2table.UnitBytes),
+					numberCell(float64(recvQ), text2table.UnitBytes),
+					pid,
+					comm,
+					textCell(fields[9]),
+				)
+			}
+		}
+
+		lines, err := readLines(filepath.Join(root, "net", "unix"))
+		if err == nil {
+			read++
+			for _, line := range lines[1:] {
+				// num refcount protocol flags type st inode path
+				fields := strings.Fields(line)
+				if len(fields) < 7 {
+					continue
+				}
+				state := unixStates[fields[5]]
+				if flags, _ := strconv.ParseUint(fields[3], 16, 64); flags&unixAcceptCon != 0 {
+					state = "LISTEN"
+				}
+				// unnamed sockets are told apart by their inode
+				path := "[" + fields[6] + "]"
+				if len(fields) > 7 {
+					path = strings.Join(fields[7:], " ")
+				}
+				pid, comm := owner(fields[6])
+
+				b.add(
+					textCell("unix"),
+					textCell(path),
+					nullCell(),
+					textCell(state),
+					nullCell(),
+					nullCell(),
+					pid,
+					comm,
+					textCell(fields[6]),
+				)
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		if read == 0 {
+			return nil, fmt.Errorf("no sockets in %s", filepath.Join(root, "net"))
+		}
+		return b.table(0, 1, 2, 8), nil
+	}
+}
+
+// hostByteOrder is the byte order of the host, which the kernel writes the
+// words of the addresses in /proc/net in.
+var hostByteOrder = func() binary.ByteOrder {
+	one := uint16(1)
+	if *(*byte)(unsafe.Pointer(&one)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}()
+
+// parseSocketAddr decodes an address from /proc/net/tcp like
+// "0100007F:0277" to "127.0.0.1:631" on a little endian host. The address
+// is in 32 bit words in the byte order of the host, given by order, and the
+// port is in hex. A zero port is a wildcard written as "*".
+func parseSocketAddr(s string, order binary.ByteOrder) (string, error) {
+
+	i := strings.IndexByte(s, ':')
+	if i < 0 {
+		return "", fmt.Errorf("bad socket address %q", s)
+	}
+	raw, err := hex.DecodeString(s[:i])
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return "", fmt.Errorf("bad socket address %q", s)
+	}
+	port, err := strconv.ParseUint(s[i+1:], 16, 16)
+	if err != nil {
+		return "", fmt.Errorf("bad socket address %q", s)
+	}
+
+	ip := make(net.IP, len(raw))
+	for w := 0; w < len(raw); w += 4 {
+		binary.BigEndian.PutUint32(ip[w:], order.Uint32(raw[w:]))
+	}
+
+	p := "*"
+	if port != 0 {
+		p = strconv.FormatUint(port, 10)
+	}
+	return net.JoinHostPort(ip.String(), p), nil
+}
+
+// socketOwner is the process a socket belongs to.
+type socketOwner struct {
+	pid  int
+	comm string
+}
+
+// socketOwners maps the inodes of sockets to the processes that have them
+// open, from the links in /proc/<pid>/fd like "socket:[12345]". The fds of
+// the processes of other users can't be read without privileges, so their
+// sockets have no owner. A socket shared by several processes belongs to
+// the one with the lowest pid, usually the parent.
+func socketOwners(root string) map[string]socketOwner {
+
+	owners := map[string]socketOwner{}
+	dir, err := os.Open(root)
+	if err != nil {
+		return owners
+	}
+	names, _ := dir.Readdirnames(-1)
+	dir.Close()
+
+	for _, name := range names {
+		pid, err := strconv.Atoi(name)
+		if err != nil {
+			continue
+		}
+		fdDir := filepath.Join(root, name, "fd")
+		fd, err := os.Open(fdDir)
+		if err != nil {
+			continue
+		}
+		fds, _ := fd.Readdirnames(-1)
+		fd.Close()
+
+		var comm string
+		for _, f := range fds {
+			link, err := os.Readlink(filepath.Join(fdDir, f))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode := strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")
+			if o, ok := owners[inode]; ok && o.pid < pid {
+				continue
+			}
+			if comm == "" {
+				data, _ := ioutil.ReadFile(filepath.Join(root, name, "comm"))
+				comm = strings.TrimSpace(string(data))
+			}
+			owners[inode] = socketOwner{pid: pid, comm: comm}
+		}
+	}
+
+	return owners
+}
@@ -0,0 +1,85 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestSocketCollector(t *testing.T) {
+	table, err := newSocketCollector("testdata/proc/t0")()
+	if err != nil {
+		t.Fatalf("collect() error = %v", err)
+	}
+
+	want := [][]string{
+		{"tcp", "0.0.0.0:8080", "0.0.0.0:*", "LISTEN", "0 B", "3 B", "42", "python3", "1001"},
+		{"tcp", "127.0.0.1:8080", "127.0.0.1:54321", "ESTABLISHED", "0 B", "0 B", "42", "python3", "1002"},
+		{"tcp", "127.0.0.1:54321", "127.0.0.1:8080", "ESTABLISHED", "512 B", "0 B", "42", "python3", "1003"},
+		{"tcp", "10.0.0.11:22", "10.0.0.1:50000", "TIME_WAIT", "0 B", "0 B", "", "", "0"},
+		{"tcp6", "[::]:22", "[::]:*", "LISTEN", "0 B", "0 B", "1", "systemd", "1004"},
+		{"tcp6", "[::1]:631", "[::1]:41668", "ESTABLISHED", "0 B", "0 B", "", "", "1005"},
+		{"udp", "127.0.0.53:53", "0.0.0.0:*", "CLOSE", "0 B", "0 B", "", "", "1006"},
+		{"unix", "/run/systemd/private", "", "LISTEN", "", "", "1", "systemd", "1007"},
+		{"unix", "[1008]", "", "CONNECTED", "", "", "1", "systemd", "1008"},
+		{"unix", "@/tmp/.X11-unix/X0", "", "CONNECTED", "", "", "", "", "1009"},
+		{"unix", "@/tmp/.X11-unix/X0", "", "CONNECTED", "", "", "", "", "1010"},
+	}
+	if got := cellTexts(table); !reflect.DeepEqual(got, want) {
+		t.Errorf("collect() rows =\n%q\nwant\n%q", got, want)
+	}
+	if got := table.Rows[1].ID; got != "tcp/127.0.0.1:8080/127.0.0.1:54321/1002" {
+		t.Errorf("collect() row id = %q, want the protocol, addresses and inode", got)
+	}
+	if len(table.Duplicates) > 0 {
+		t.Errorf("collect() duplicates = %q", table.Duplicates)
+	}
+}
+
+func TestParseSocketAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		order   binary.ByteOrder
+		want    string
+		wantErr bool
+	}{
+		{addr: "0100007F:0277", want: "127.0.0.1:631"},
+		{addr: "00000000:0000", want: "0.0.0.0:*"},
+		{addr: "B80D01200000000067452301EFCDAB89:01BB", want: "[2001:db8::123:4567:89ab:cdef]:443"},
+		{addr: "0000000000000000FFFF00000100007F:1F90", want: "127.0.0.1:8080"},
+		{addr: "7F000001:0277", order: binary.BigEndian, want: "127.0.0.1:631"},
+		{addr: "20010DB8000000000123456789ABCDEF:01BB", order: binary.BigEndian, want: "[2001:db8::123:4567:89ab:cdef]:443"},
+		{addr: "0100007F", wantErr: true},
+		{addr: "0100:0277", wantErr: true},
+		{addr: "0100007F:xyz", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			order := tt.order
+			if order == nil {
+				order = binary.LittleEndian
+			}
+			got, err := parseSocketAddr(tt.addr, order)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSocketAddr() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseSocketAddr() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
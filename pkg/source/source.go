@@ -0,0 +1,186 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package source runs and reads the inputs tables are shown from. Sources
+// are made from specs like "cmd:ps aux" or "file:/tmp/status.csv" by the
+// constructor registered for the scheme before the colon.
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cove/oview/pkg/text2table"
+)
+
+// Frame is a table read from a source, with when and where it was read.
+type Frame struct {
+	Table *text2table.Table
+	// Time the table was read
+	Time time.Time
+	// Source is the name of the source the table was read from
+	Source string
+}
+
+// Source is an input tables are read from.
+type Source interface {
+	// Name of the source for its status, like the command line
+	Name() string
+	// Run reads tables from the source and passes them to emit until ctx
+	// is done, returning nil, or until the source has no more tables to
+	// read like a closed stdin. Failures of polled sources are retried and
+	// reported in their status rather than returned.
+	Run(ctx context.Context, emit func(Frame)) error
+}
+
+// Config holds the settings that sources are made with.
+type Config struct {
+	// Options the tables are parsed with
+	Options text2table.Options
+	// Interval between the reads of a source that's polled, which is also
+	// how long a stream waits for a table to be complete
+	Interval time.Duration
+	// Timeout a read can go without progress before it's stopped, with no
+	// limit when zero
+	Timeout time.Duration
+	// Deadline is the longest a command can run, with no limit when zero
+	Deadline time.Duration
+	// Expire is how long a sender to a listener can go quiet before its
+	// rows are dropped, never when zero
+	Expire time.Duration
+	// Delimiter is the line separating the tables in a stream, detected
+	// when empty
+	Delimiter string
+	// Follow has files tailed, showing the lines appended to them under
+	// their header, and only the last Window of them, DefaultWindow when
+	// zero or all of them when negative
+	Follow bool
+	Window int
+	// Shell and Dir the commands are run with
+	Shell string
+	Dir   string
+	// Tag has the tables sent by each connection to a listener shown
+	// together, told apart by a SOURCE column
+	Tag bool
+	// Sum names the groups of the regexp summed by the log sources, over
+	// the Span of the last lines counted. They count the lines matching the
+	// regexp in the Options by its groups named by the Key there.
+	Sum  []string
+	Span time.Duration
+	// Labels of the containers shown as columns by the docker source
+	Labels []string
+	// Method and Header the URLs are requested with, a GET when empty
+	Method string
+	Header http.Header
+	// OnStatus is called with the status of a polled source whenever it
+	// changes
+	OnStatus func(Status)
+}
+
+// warn shows an error that didn't stop a source that isn't polled, like a
+// table it couldn't parse, in its status, or clears it when nil.
+func (cfg Config) warn(name string, err error) {
+	if cfg.OnStatus != nil {
+		cfg.OnStatus(Status{Name: name, Warning: err})
+	}
+}
+
+// Spec names a source, like "cmd:ps aux", as a scheme and an argument.
+type Spec struct {
+	Scheme string
+	Arg    string
+}
+
+// ParseSpec splits a spec at the first colon into its scheme and argument.
+// A "-" is short for "stdin:".
+func ParseSpec(s string) (Spec, error) {
+	if s == "-" {
+		return Spec{Scheme: "stdin"}, nil
+	}
+	i := strings.Index(s, ":")
+	if i <= 0 {
+		return Spec{}, fmt.Errorf("source %q has no scheme, like cmd:, file: or stdin:", s)
+	}
+	scheme := strings.ToLower(s[:i])
+	for _, r := range scheme {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '+' || r == '-' || r == '.') {
+			return Spec{}, fmt.Errorf("source %q has an invalid scheme %q", s, s[:i])
+		}
+	}
+	return Spec{Scheme: scheme, Arg: s[i+1:]}, nil
+}
+
+// String returns the spec as it's written.
+func (s Spec) String() string {
+	return s.Scheme + ":" + s.Arg
+}
+
+// Factory makes a source from its spec.
+type Factory func(spec Spec, cfg Config) (Source, error)
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]Factory{}
+)
+
+// Register makes the sources of a scheme with the factory, replacing any
+// registered before.
+func Register(scheme string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[strings.ToLower(scheme)] = factory
+}
+
+// Schemes returns the registered schemes in order.
+func Schemes() []string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	var schemes []string
+	for scheme := range registry {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// New makes the source named by the spec.
+func New(spec string, cfg Config) (Source, error) {
+	s, err := ParseSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	registryMu.Lock()
+	factory, ok := registry[s.Scheme]
+	registryMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown source %q, use one of %s:", spec, strings.Join(Schemes(), ":, "))
+	}
+	return factory(s, cfg)
+}
+
+// supervisor returns a supervisor for a polled source with the config.
+func (cfg Config) supervisor(name string) *Supervisor {
+	return &Supervisor{
+		Name:     name,
+		Interval: cfg.Interval,
+		Timeout:  cfg.Timeout,
+		OnStatus: cfg.OnStatus,
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSpec(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Spec
+		wantErr bool
+	}{
+		{spec: "cmd:ps aux", want: Spec{Scheme: "cmd", Arg: "ps aux"}},
+		{spec: "CMD:ps -o pid:8", want: Spec{Scheme: "cmd", Arg: "ps -o pid:8"}},
+		{spec: "file:/tmp/a.csv", want: Spec{Scheme: "file", Arg: "/tmp/a.csv"}},
+		{spec: "stdin:", want: Spec{Scheme: "stdin"}},
+		{spec: "-", want: Spec{Scheme: "stdin"}},
+		{spec: "https://example.com/status", want: Spec{Scheme: "https", Arg: "//example.com/status"}},
+		{spec: "ps aux", wantErr: true},
+		{spec: ":ps", wantErr: true},
+		{spec: "ps aux | grep a:b", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseSpec(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSpec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSpec() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		spec    string
+		name    string
+		wantErr bool
+	}{
+		{spec: "cmd:ps aux | grep java", name: "ps aux | grep java"},
+		{spec: "file:/tmp/a.csv", name: "/tmp/a.csv"},
+		{spec: "stdin:", name: "stdin"},
+		{spec: "cmd:", wantErr: true},
+		{spec: "file:", wantErr: true},
+		{spec: "nope:x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			src, err := New(tt.spec, Config{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && src.Name() != tt.name {
+				t.Errorf("New() name = %q, want %q", src.Name(), tt.name)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("test", func(spec Spec, cfg Config) (Source, error) {
+		return &stdinSource{r: strings.NewReader(spec.Arg), cfg: cfg}, nil
+	})
+	registered := map[string]bool{}
+	for _, scheme := range Schemes() {
+		registered[scheme] = true
+	}
+	for _, scheme := range []string{"cmd", "file", "stdin", "test"} {
+		if !registered[scheme] {
+			t.Errorf("Schemes() = %q, want %s in it", Schemes(), scheme)
+		}
+	}
+
+	src, err := New("test:a b\n1 2\n", Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := collect(t, src, 1)
+	if len(frames) != 1 || frames[0].Source != "stdin" || len(frames[0].Table.Rows) != 1 {
+		t.Errorf("Run() frames = %+v", frames)
+	}
+}
+
+func TestSourcesRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oview")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "jobs.csv")
+	if err := ioutil.WriteFile(path, []byte("job,done\nbuild,3\ntest,5\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		spec   string
+		rows   int
+		frames int
+	}{
+		// files are read again when they change
+		{spec: "file:" + path, rows: 2, frames: 1},
+		{spec: "cmd:printf 'job done\\nbuild 3\\n' | cat", rows: 1, frames: 2},
+		{spec: "cmd:cat " + path, rows: 2, frames: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			var statuses []Status
+			src, err := New(tt.spec, Config{
+				Interval: 200 * time.Millisecond,
+				Shell:    DefaultShell,
+				OnStatus: func(st Status) { statuses = append(statuses, st) },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			frames := collect(t, src, tt.frames)
+			if len(frames) != tt.frames {
+				t.Errorf("Run() %d frames, want %d", len(frames), tt.frames)
+			}
+			for _, frame := range frames {
+				if frame.Source != src.Name() || frame.Time.IsZero() || len(frame.Table.Rows) != tt.rows {
+					t.Errorf("Run() frame = %+v, want %d rows from %s", frame, tt.rows, src.Name())
+				}
+			}
+			if len(statuses) == 0 || statuses[0].Name != src.Name() {
+				t.Errorf("Run() statuses = %+v", statuses)
+			}
+		})
+	}
+}
+
+// collect runs the source until it emits n frames or ends.
+func collect(t *testing.T, src Source, n int) []Frame {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var frames []Frame
+	done := make(chan error, 1)
+	go func() {
+		done <- src.Run(ctx, func(frame Frame) {
+			if len(frames) < n {
+				frames = append(frames, frame)
+			}
+			if len(frames) == n {
+				cancel()
+			}
+		})
+	}()
+	if err := <-done; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+	return frames
+}
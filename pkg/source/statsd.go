@@ -0,0 +1,106 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cove/oview/pkg/text2table"
+)
+
+func init() {
+	Register("statsd", func(spec Spec, cfg Config) (Source, error) {
+		return newMetricsSource(spec, cfg, "udp://127.0.0.1:8125", parseStatsd)
+	})
+}
+
+// statsdKinds are the kinds of the StatsD metric types.
+var statsdKinds = map[string]metricKind{
+	"c": counter, "g": gauge, "ms": timer, "h": timer, "d": timer, "s": set,
+}
+
+// parseStatsd parses a StatsD metric like "api.requests:1|c|@0.1", with
+// tags in the DogStatsD style "|#host:web1,env:prod" or the InfluxDB one
+// "api.requests,host=web1:1|c". Timings in milliseconds are in seconds.
+func parseStatsd(line string) ([]point, error) {
+
+	pipe := strings.Index(line, "|")
+	colon := -1
+	if pipe > 0 {
+		colon = strings.LastIndex(line[:pipe], ":")
+	}
+	if colon <= 0 {
+		return nil, fmt.Errorf("statsd metric %q isn't like name:value|type", line)
+	}
+
+	p := point{field: "value", rate: 1}
+	name := line[:colon]
+	parts := strings.Split(line[colon+1:], "|")
+	value, typ := parts[0], parts[1]
+
+	// tags in the name like "api.requests,host=web1"
+	fields := strings.Split(name, ",")
+	p.name = fields[0]
+	for _, t := range fields[1:] {
+		kv := strings.SplitN(t, "=", 2)
+		if len(kv) == 2 {
+			p.tags = append(p.tags, metricTag{key: kv[0], value: kv[1]})
+		}
+	}
+
+	kind, ok := statsdKinds[typ]
+	if !ok {
+		return nil, fmt.Errorf("statsd metric %q has an unknown type %q", line, typ)
+	}
+	p.kind = kind
+
+	for _, part := range parts[2:] {
+		switch {
+		case strings.HasPrefix(part, "@"):
+			rate, err := strconv.ParseFloat(part[1:], 64)
+			if err != nil {
+				return nil, fmt.Errorf("statsd metric %q has an invalid sample rate", line)
+			}
+			p.rate = rate
+		case strings.HasPrefix(part, "#"):
+			for _, t := range strings.Split(part[1:], ",") {
+				kv := strings.SplitN(t, ":", 2)
+				if len(kv) == 1 {
+					kv = append(kv, "")
+				}
+				p.tags = append(p.tags, metricTag{key: kv[0], value: kv[1]})
+			}
+		}
+	}
+	sort.SliceStable(p.tags, func(i, j int) bool { return p.tags[i].key < p.tags[j].key })
+
+	if kind == set {
+		p.text = value
+		return []point{p}, nil
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("statsd metric %q has an invalid value %q", line, value)
+	}
+	p.value = n
+	p.delta = kind == gauge && (value[0] == '+' || value[0] == '-')
+	if typ == "ms" {
+		p.value, p.unit = n/1000, text2table.UnitSeconds
+	}
+	return []point{p}, nil
+}
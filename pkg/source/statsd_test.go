@@ -0,0 +1,95 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cove/oview/pkg/text2table"
+)
+
+func TestParseStatsd(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    point
+		wantErr bool
+	}{
+		{
+			name: "counter",
+			line: "api.requests:1|c",
+			want: point{name: "api.requests", field: "value", kind: counter, value: 1, rate: 1},
+		},
+		{
+			name: "sampled",
+			line: "api.requests:2|c|@0.1",
+			want: point{name: "api.requests", field: "value", kind: counter, value: 2, rate: 0.1},
+		},
+		{
+			name: "timer in seconds",
+			line: "api.latency:12.5|ms",
+			want: point{name: "api.latency", field: "value", kind: timer, value: 0.0125, unit: text2table.UnitSeconds, rate: 1},
+		},
+		{
+			name: "gauge change",
+			line: "queue.depth:-3|g",
+			want: point{name: "queue.depth", field: "value", kind: gauge, value: -3, delta: true, rate: 1},
+		},
+		{
+			name: "set",
+			line: "users:alice|s",
+			want: point{name: "users", field: "value", kind: set, text: "alice", rate: 1},
+		},
+		{
+			name: "dogstatsd tags",
+			line: "api.requests:1|c|#region:eu,host:web1,canary",
+			want: point{name: "api.requests", field: "value", kind: counter, value: 1, rate: 1,
+				tags: []metricTag{{"canary", ""}, {"host", "web1"}, {"region", "eu"}}},
+		},
+		{
+			name: "influx tags",
+			line: "api.requests,host=web1,region=eu:1|c",
+			want: point{name: "api.requests", field: "value", kind: counter, value: 1, rate: 1,
+				tags: []metricTag{{"host", "web1"}, {"region", "eu"}}},
+		},
+		{
+			name:    "no type",
+			line:    "api.requests:1",
+			wantErr: true,
+		},
+		{
+			name:    "unknown type",
+			line:    "api.requests:1|x",
+			wantErr: true,
+		},
+		{
+			name:    "not a number",
+			line:    "api.requests:one|c",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseStatsd(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseStatsd() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, []point{tt.want}) {
+				t.Errorf("parseStatsd() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
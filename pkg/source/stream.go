@@ -0,0 +1,126 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/cove/oview/pkg/text2table"
+)
+
+// streamTables reads the frames printed to r until it's closed or ctx is
+// done, emitting the table in each one as soon as it's complete. A frame
+// that has been waiting for more output for an interval is emitted as is,
+// for commands like `kubectl get -w` that print changes as they happen, and
+// unless the output is polled, so is one that's still growing an interval
+// after its first line, for streams that never pause. The output of a
+// polled command is otherwise only complete when it exits. A frame that
+// can't be parsed is passed to warn, which is called with nil once one can
+// be again.
+func streamTables(ctx context.Context, r io.Reader, name string, cfg Config, polled bool, warn func(error), emit func(Frame)) error {
+
+	lines := make(chan string)
+	failed := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+		failed <- scanner.Err()
+	}()
+
+	warned := false
+	send := func(frame []string) {
+		if frame == nil {
+			return
+		}
+		table, err := text2table.ReadTable(strings.NewReader(strings.Join(frame, "\n")), cfg.Options)
+		if err != nil {
+			warn(fmt.Errorf("failed to parse a table: %s", err))
+			warned = true
+			return
+		}
+		if warned {
+			warn(nil)
+			warned = false
+		}
+		if len(table.Columns) > 0 {
+			emit(Frame{Table: table, Time: time.Now(), Source: name})
+		}
+	}
+
+	idle := cfg.Interval
+	if idle <= 0 {
+		idle = time.Second
+	}
+	timer := time.NewTimer(idle)
+	defer timer.Stop()
+
+	// started is when the first line of the frame being read came
+	var started time.Time
+	frames := text2table.NewFrameSplitter(cfg.Delimiter)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case line, ok := <-lines:
+			if !ok {
+				send(frames.Flush())
+				select {
+				case err := <-failed:
+					return err
+				default:
+					return nil
+				}
+			}
+			if done := frames.Add(line); len(done) > 0 {
+				for _, frame := range done {
+					send(frame)
+				}
+				started = time.Now()
+			} else if started.IsZero() {
+				started = time.Now()
+			}
+			if !polled && time.Since(started) >= idle {
+				send(frames.Flush())
+				started = time.Time{}
+			}
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(idle)
+
+		case <-timer.C:
+			send(frames.Flush())
+			started = time.Time{}
+			timer.Reset(idle)
+		}
+	}
+}
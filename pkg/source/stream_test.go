@@ -0,0 +1,182 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cove/oview/pkg/text2table"
+)
+
+func TestStreamTables(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		delimiter string
+		rows      []int
+	}{
+		{
+			name:  "repeated header",
+			input: "pid cpu\n1 0.5\n2 1.0\npid cpu\n1 0.7\n",
+			rows:  []int{2, 1},
+		},
+		{
+			name:      "delimiter",
+			input:     "name,size\na,1\n--\nname,size\na,2\nb,3\n",
+			delimiter: "--",
+			rows:      []int{1, 2},
+		},
+		{
+			name:  "empty",
+			input: "\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rows []int
+			cfg := Config{Interval: time.Minute, Delimiter: tt.delimiter}
+			err := streamTables(context.Background(), strings.NewReader(tt.input), "test", cfg, false, func(error) {}, func(frame Frame) {
+				rows = append(rows, len(frame.Table.Rows))
+			})
+			if err != nil {
+				t.Fatalf("streamTables() error = %v", err)
+			}
+			if len(rows) != len(tt.rows) {
+				t.Fatalf("streamTables() frames with rows %v, want %v", rows, tt.rows)
+			}
+			for i := range rows {
+				if rows[i] != tt.rows[i] {
+					t.Errorf("streamTables() frames with rows %v, want %v", rows, tt.rows)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamTablesIdle(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+
+	frames := make(chan Frame, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- streamTables(ctx, r, "test", Config{Interval: 50 * time.Millisecond}, false, func(error) {}, func(frame Frame) {
+			frames <- frame
+		})
+	}()
+
+	// a table with no end in sight is emitted after the interval
+	w.Write([]byte("NAME READY\nweb 1/1\n"))
+	select {
+	case frame := <-frames:
+		if len(frame.Table.Rows) != 1 {
+			t.Errorf("streamTables() rows = %d, want 1", len(frame.Table.Rows))
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("streamTables() emitted nothing after the interval")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("streamTables() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("streamTables() didn't return when ctx was done")
+	}
+}
+
+func TestStreamTablesBusy(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+
+	frames := make(chan Frame, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go streamTables(ctx, r, "test", Config{Interval: 50 * time.Millisecond}, false, func(error) {}, func(frame Frame) {
+		select {
+		case frames <- frame:
+		default:
+		}
+	})
+
+	// a command that never pauses still has its table emitted every
+	// interval
+	go func() {
+		w.Write([]byte("NAME READY\n"))
+		for ctx.Err() == nil {
+			w.Write([]byte("web 1/1\n"))
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	select {
+	case frame := <-frames:
+		if len(frame.Table.Rows) == 0 {
+			t.Error("streamTables() emitted a table with no rows")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("streamTables() emitted nothing while the output kept coming")
+	}
+}
+
+func TestStreamTablesPolled(t *testing.T) {
+	r, w := io.Pipe()
+
+	var rows []int
+	done := make(chan error, 1)
+	go func() {
+		done <- streamTables(context.Background(), r, "test", Config{Interval: 50 * time.Millisecond}, true, func(error) {}, func(frame Frame) {
+			rows = append(rows, len(frame.Table.Rows))
+		})
+	}()
+
+	// the output of a polled command that never pauses is only emitted
+	// once the command exits
+	w.Write([]byte("NAME READY\n"))
+	n := 0
+	for start := time.Now(); time.Since(start) < 150*time.Millisecond; n++ {
+		w.Write([]byte("web 1/1\n"))
+	}
+	w.Close()
+
+	if err := <-done; err != nil {
+		t.Fatalf("streamTables() error = %v", err)
+	}
+	if len(rows) != 1 || rows[0] != n {
+		t.Errorf("streamTables() frames with rows %v, want [%d]", rows, n)
+	}
+}
+
+func TestStreamTablesWarn(t *testing.T) {
+	var warnings []error
+	cfg := Config{Interval: time.Minute, Options: text2table.Options{Regexp: "("}}
+	err := streamTables(context.Background(), strings.NewReader("a b\n1 2\n"), "test", cfg, false, func(err error) {
+		warnings = append(warnings, err)
+	}, func(Frame) {
+		t.Error("streamTables() emitted a table it couldn't parse")
+	})
+	if err != nil {
+		t.Fatalf("streamTables() error = %v", err)
+	}
+	if len(warnings) != 1 || warnings[0] == nil {
+		t.Errorf("streamTables() warnings = %v, want the parse error", warnings)
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// DefaultMaxBackoff is the longest a supervisor waits to retry a source that
+// keeps failing, unless another limit is given.
+const DefaultMaxBackoff = time.Minute
+
+// ErrTimeout is the error of a run that was stopped for making no progress
+// within the timeout.
+var ErrTimeout = errors.New("timed out")
+
+// Runner does a single run of a source, like running a command once or
+// reading a file, until it's done or ctx is. It calls alive whenever it
+// makes progress, like reading some output, to hold off the timeout.
+type Runner func(ctx context.Context, alive func()) error
+
+// Status is the health of a source as recorded by its supervisor.
+type Status struct {
+	// Name of the source, like the command line
+	Name string
+	// Running is whether a run is in progress
+	Running bool
+	// LastSuccess is when a run last finished without an error
+	LastSuccess time.Time
+	// LastError is the error of the last run that failed and LastFailure
+	// when it did
+	LastError   error
+	LastFailure time.Time
+	// Failures counts the runs that failed in a row, since the last success
+	Failures int
+	// Duration is how long the last run took
+	Duration time.Duration
+	// Next is when the next run starts, while waiting for it
+	Next time.Time
+	// Warning is the last error that didn't stop the source, like a table
+	// it couldn't parse, until it reads one again
+	Warning error
+}
+
+// String summarizes the status in a line, like
+// "ps aux: ok at 15:04:05 in 120ms".
+func (s Status) String() string {
+	const clock = "15:04:05"
+	switch {
+	case s.Failures > 0:
+		text := fmt.Sprintf("%s: failed %d times, last at %s after %s", s.Name,
+			s.Failures, s.LastFailure.Format(clock), round(s.Duration))
+		if s.Failures == 1 {
+			text = fmt.Sprintf("%s: failed at %s after %s", s.Name,
+				s.LastFailure.Format(clock), round(s.Duration))
+		}
+		if !s.Running && !s.Next.IsZero() {
+			text += ", retrying at " + s.Next.Format(clock)
+		}
+		return text
+	case s.Warning != nil:
+		return fmt.Sprintf("%s: %s", s.Name, s.Warning)
+	case !s.LastSuccess.IsZero():
+		return fmt.Sprintf("%s: ok at %s in %s", s.Name, s.LastSuccess.Format(clock), round(s.Duration))
+	case s.Running:
+		return s.Name + ": starting"
+	}
+	return s.Name
+}
+
+// round drops the precision of a duration that's too fine to be of interest.
+func round(d time.Duration) time.Duration {
+	if d >= time.Second {
+		return d.Round(100 * time.Millisecond)
+	}
+	return d.Round(time.Millisecond)
+}
+
+// Supervisor runs a source again and again, an interval apart. A run that
+// makes no progress within the timeout or goes on past the deadline is
+// stopped, and after a failure the wait before the next run doubles, give or
+// take some jitter so sources failing together don't retry together, up to
+// the longest backoff.
+type Supervisor struct {
+	// Name of the source for its status
+	Name string
+	// Interval between the end of a run and the start of the next
+	Interval time.Duration
+	// Timeout a run can go without progress before it's stopped, with no
+	// limit when zero
+	Timeout time.Duration
+	// Deadline is the longest a run can take, progress or not, with no
+	// limit when zero
+	Deadline time.Duration
+	// MaxBackoff is the longest wait after failures, DefaultMaxBackoff when
+	// zero
+	MaxBackoff time.Duration
+	// OnStatus is called with the status whenever it changes
+	OnStatus func(Status)
+	// Wake starts the next run when it receives, like when a file that's
+	// watched changes. After a run that succeeded the next one waits for
+	// it rather than the interval.
+	Wake <-chan struct{}
+
+	mu     sync.Mutex
+	status Status
+	random func() float64
+}
+
+// Status returns the current status of the source.
+func (s *Supervisor) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// Run runs the source until ctx is done.
+func (s *Supervisor) Run(ctx context.Context, run Runner) {
+	for {
+		err := s.runOnce(ctx, run)
+		if ctx.Err() != nil {
+			return
+		}
+
+		var timeout <-chan time.Time
+		switch {
+		case err != nil:
+			wait := s.backoff(s.Status().Failures)
+			s.update(func(st *Status) { st.Next = time.Now().Add(wait) })
+			timeout = time.After(wait)
+		case s.Wake == nil:
+			s.update(func(st *Status) { st.Next = time.Now().Add(s.Interval) })
+			timeout = time.After(s.Interval)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-timeout:
+		case <-s.Wake:
+		}
+	}
+}
+
+// runOnce does a single run, stopping it when it makes no progress within
+// the timeout or reaches the deadline, and records how it went.
+func (s *Supervisor) runOnce(ctx context.Context, run Runner) error {
+
+	s.update(func(st *Status) {
+		st.Name = s.Name
+		st.Running = true
+		st.Next = time.Time{}
+		st.Warning = nil
+	})
+
+	var runCtx context.Context
+	var cancel context.CancelFunc
+	if s.Deadline > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, s.Deadline)
+	} else {
+		runCtx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	alive := func() {}
+	timedOut := make(chan struct{})
+	if s.Timeout > 0 {
+		var once sync.Once
+		timer := time.AfterFunc(s.Timeout, func() {
+			once.Do(func() { close(timedOut) })
+			cancel()
+		})
+		defer timer.Stop()
+		alive = func() { timer.Reset(s.Timeout) }
+	}
+
+	start := time.Now()
+	err := run(runCtx, alive)
+	select {
+	case <-timedOut:
+		err = fmt.Errorf("%s with no progress for %s", ErrTimeout, s.Timeout)
+	default:
+		if ctx.Err() == nil && runCtx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("%s after running for %s", ErrTimeout, s.Deadline)
+		}
+	}
+	end := time.Now()
+
+	s.update(func(st *Status) {
+		st.Running = false
+		st.Duration = end.Sub(start)
+		if err != nil {
+			st.LastError = err
+			st.LastFailure = end
+			st.Failures++
+		} else {
+			st.LastSuccess = end
+			st.Failures = 0
+		}
+	})
+
+	return err
+}
+
+// backoff returns how long to wait after the given number of failures in a
+// row: the interval, but at least a second, doubled for every failure after
+// the first, up to the longest backoff, plus or minus a quarter.
+func (s *Supervisor) backoff(failures int) time.Duration {
+
+	max := s.MaxBackoff
+	if max <= 0 {
+		max = DefaultMaxBackoff
+	}
+	wait := s.Interval
+	if wait < time.Second {
+		wait = time.Second
+	}
+	for i := 1; i < failures && wait < max; i++ {
+		wait *= 2
+	}
+	if wait > max {
+		wait = max
+	}
+
+	random := s.random
+	if random == nil {
+		random = rand.Float64
+	}
+	return time.Duration(float64(wait) * (0.75 + random()/2))
+}
+
+// update changes the status and passes it on.
+func (s *Supervisor) update(change func(*Status)) {
+	s.mu.Lock()
+	change(&s.status)
+	st := s.status
+	s.mu.Unlock()
+
+	if s.OnStatus != nil {
+		s.OnStatus(st)
+	}
+}
+
+// warn records an error that didn't stop the run, or clears it when nil.
+func (s *Supervisor) warn(err error) {
+	s.update(func(st *Status) { st.Warning = err })
+}
+
+// ProgressReader returns a reader that calls alive whenever it reads
+// something from r, to hold off the timeout of a run reading output.
+func ProgressReader(r io.Reader, alive func()) io.Reader {
+	return &progressReader{r: r, alive: alive}
+}
+
+type progressReader struct {
+	r     io.Reader
+	alive func()
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.alive()
+	}
+	return n, err
+}
@@ -0,0 +1,182 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSupervisorTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{name: "command", line: "sleep 10"},
+		// cat keeps the pipe open unless the whole group is killed
+		{name: "pipeline", line: "sleep 10 | cat"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Supervisor{Name: tt.line, Timeout: 100 * time.Millisecond}
+			command := Command{Line: tt.line, Shell: DefaultShell}
+			start := time.Now()
+			err := s.runOnce(context.Background(), func(ctx context.Context, alive func()) error {
+				return command.Run(ctx, nil, func(stdout io.Reader) {
+					ioutil.ReadAll(ProgressReader(stdout, alive))
+				})
+			})
+			if err == nil || !strings.HasPrefix(err.Error(), ErrTimeout.Error()) {
+				t.Errorf("runOnce() error = %v, want a timeout", err)
+			}
+			if d := time.Since(start); d > 5*time.Second {
+				t.Errorf("runOnce() took %s, want it killed after the timeout", d)
+			}
+		})
+	}
+}
+
+func TestSupervisorAlive(t *testing.T) {
+	s := &Supervisor{Name: "chatty", Timeout: 100 * time.Millisecond}
+	err := s.runOnce(context.Background(), func(ctx context.Context, alive func()) error {
+		for i := 0; i < 10; i++ {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(30 * time.Millisecond):
+				alive()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("runOnce() error = %v, want none while making progress", err)
+	}
+}
+
+func TestSupervisorDeadline(t *testing.T) {
+	s := &Supervisor{Name: "yes", Timeout: time.Second, Deadline: 100 * time.Millisecond}
+	command := Command{Line: "yes", Shell: DefaultShell}
+	start := time.Now()
+	err := s.runOnce(context.Background(), func(ctx context.Context, alive func()) error {
+		return command.Run(ctx, nil, func(stdout io.Reader) {
+			ioutil.ReadAll(ProgressReader(stdout, alive))
+		})
+	})
+	if err == nil || !strings.HasPrefix(err.Error(), ErrTimeout.Error()) {
+		t.Errorf("runOnce() error = %v, want a timeout", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("runOnce() took %s, want it killed at the deadline", d)
+	}
+}
+
+func TestSupervisorStatus(t *testing.T) {
+	var seen []Status
+	s := &Supervisor{Name: "flaky", OnStatus: func(st Status) { seen = append(seen, st) }}
+
+	results := []error{errors.New("one"), errors.New("two"), nil, errors.New("three")}
+	failures := []int{1, 2, 0, 1}
+	for i, result := range results {
+		s.runOnce(context.Background(), func(ctx context.Context, alive func()) error {
+			if !s.Status().Running {
+				t.Errorf("run %d: Running = false during the run", i)
+			}
+			return result
+		})
+		st := s.Status()
+		if st.Running || st.Failures != failures[i] {
+			t.Errorf("run %d: Running = %v, Failures = %d, want false, %d", i, st.Running, st.Failures, failures[i])
+		}
+		if result != nil && st.LastError != result {
+			t.Errorf("run %d: LastError = %v, want %v", i, st.LastError, result)
+		}
+	}
+	if st := s.Status(); st.LastSuccess.IsZero() || st.LastFailure.Before(st.LastSuccess) {
+		t.Errorf("LastSuccess = %s, LastFailure = %s", st.LastSuccess, st.LastFailure)
+	}
+	if len(seen) != 2*len(results) {
+		t.Errorf("OnStatus called %d times, want %d", len(seen), 2*len(results))
+	}
+}
+
+func TestSupervisorBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		max      time.Duration
+		random   float64
+		failures int
+		want     time.Duration
+	}{
+		{name: "first", interval: 3 * time.Second, random: 0.5, failures: 1, want: 3 * time.Second},
+		{name: "doubles", interval: 3 * time.Second, random: 0.5, failures: 3, want: 12 * time.Second},
+		{name: "at least a second", random: 0.5, failures: 2, want: 2 * time.Second},
+		{name: "capped", interval: 3 * time.Second, max: 10 * time.Second, random: 0.5, failures: 30, want: 10 * time.Second},
+		{name: "default cap", interval: 3 * time.Second, random: 0.5, failures: 30, want: DefaultMaxBackoff},
+		{name: "jitter down", interval: 4 * time.Second, random: 0, failures: 1, want: 3 * time.Second},
+		{name: "jitter up", interval: 4 * time.Second, random: 1, failures: 1, want: 5 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Supervisor{Interval: tt.interval, MaxBackoff: tt.max, random: func() float64 { return tt.random }}
+			if got := s.backoff(tt.failures); got != tt.want {
+				t.Errorf("backoff() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusString(t *testing.T) {
+	at := time.Date(2026, 10, 18, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name   string
+		status Status
+		want   string
+	}{
+		{
+			name:   "starting",
+			status: Status{Name: "ps aux", Running: true},
+			want:   "ps aux: starting",
+		},
+		{
+			name:   "ok",
+			status: Status{Name: "ps aux", LastSuccess: at, Duration: 123456 * time.Microsecond},
+			want:   "ps aux: ok at 15:04:05 in 123ms",
+		},
+		{
+			name:   "failed",
+			status: Status{Name: "ps aux", LastFailure: at, Failures: 1, Duration: 2345 * time.Millisecond, Next: at.Add(3 * time.Second)},
+			want:   "ps aux: failed at 15:04:05 after 2.3s, retrying at 15:04:08",
+		},
+		{
+			name:   "failing",
+			status: Status{Name: "ps aux", LastSuccess: at, LastFailure: at, Failures: 3, Running: true},
+			want:   "ps aux: failed 3 times, last at 15:04:05 after 0s",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.status.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
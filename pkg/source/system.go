@@ -0,0 +1,318 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/cove/oview/pkg/text2table"
+)
+
+func init() {
+	Register("cpu", newSystemSource(newCPUCollector))
+	Register("disk", newSystemSource(newDiskCollector))
+	Register("net", newSystemSource(newNetCollector))
+}
+
+// sectorSize is the unit of the sectors in /proc/diskstats, whatever the
+// sector size of the disk.
+const sectorSize = 512
+
+// newSystemSource returns the factory of a source of the hardware read from
+// /proc, or from the proc mounted where the spec says.
+func newSystemSource(collector func(root string) func() (*text2table.Table, error)) Factory {
+	return func(spec Spec, cfg Config) (Source, error) {
+		root := spec.Arg
+		if root == "" {
+			root = procRoot
+		}
+		return &collectorSource{name: spec.String(), collect: collector(root), cfg: cfg}, nil
+	}
+}
+
+// readUptime returns the seconds since boot, which is the clock the rates
+// of the counters in proc are worked out with.
+func readUptime(root string) (float64, error) {
+	data, err := ioutil.ReadFile(filepath.Join(root, "uptime"))
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, errors.New("empty uptime")
+	}
+	return strconv.ParseFloat(fields[0], 64)
+}
+
+// counterRates works out the rates per second of counters that only go up,
+// like the bytes sent by an interface, between one sample and the next.
+type counterRates struct {
+	last, next map[string][]uint64
+	uptime     float64
+}
+
+// rates returns the rates of the counters of the key since the last sample,
+// or since boot if there wasn't one. A counter that went down was reset,
+// so it's counted from zero.
+func (r *counterRates) rates(key string, counters []uint64, uptime float64) []float64 {
+
+	if r.next == nil {
+		r.next = map[string][]uint64{}
+	}
+	r.next[key] = counters
+
+	last, ok := r.last[key]
+	elapsed := uptime - r.uptime
+	if !ok || len(last) != len(counters) || elapsed <= 0 {
+		last, elapsed = make([]uint64, len(counters)), uptime
+	}
+
+	rates := make([]float64, len(counters))
+	for i, n := range counters {
+		delta := n
+		if n >= last[i] {
+			delta = n - last[i]
+		}
+		if elapsed > 0 {
+			rates[i] = float64(delta) / elapsed
+		}
+	}
+	return rates
+}
+
+// done makes the samples taken since the last call the ones the next rates
+// are worked out from.
+func (r *counterRates) done(uptime float64) {
+	r.last, r.next, r.uptime = r.next, nil, uptime
+}
+
+// readLines reads the lines of a file in proc.
+func readLines(path string) ([]string, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// parseCounters parses fields of counters, taking a bad one as zero.
+func parseCounters(fields []string) []uint64 {
+	counters := make([]uint64, len(fields))
+	for i, f := range fields {
+		counters[i], _ = strconv.ParseUint(f, 10, 64)
+	}
+	return counters
+}
+
+// cpuColumns are the columns of the table of CPUs, in percent of the time.
+var cpuColumns = []text2table.Column{
+	{Name: "CPU", Type: text2table.Identifier},
+	{Name: "%BUSY", Type: text2table.Numeric, Unit: text2table.UnitPercent},
+	{Name: "%USER", Type: text2table.Numeric, Unit: text2table.UnitPercent},
+	{Name: "%SYSTEM", Type: text2table.Numeric, Unit: text2table.UnitPercent},
+	{Name: "%IOWAIT", Type: text2table.Numeric, Unit: text2table.UnitPercent},
+	{Name: "%STEAL", Type: text2table.Numeric, Unit: text2table.UnitPercent},
+	{Name: "%IDLE", Type: text2table.Numeric, Unit: text2table.UnitPercent},
+}
+
+// newCPUCollector returns a collector of the usage of each CPU core from
+// /proc/stat.
+func newCPUCollector(root string) func() (*text2table.Table, error) {
+	var r counterRates
+	return func() (*text2table.Table, error) {
+
+		uptime, err := readUptime(root)
+		if err != nil {
+			return nil, err
+		}
+		lines, err := readLines(filepath.Join(root, "stat"))
+		if err != nil {
+			return nil, err
+		}
+
+		b := newTableBuilder(cpuColumns...)
+		for _, line := range lines {
+			// the line of all the CPUs is "cpu" and the cores are numbered
+			fields := strings.Fields(line)
+			if len(fields) < 9 || !strings.HasPrefix(fields[0], "cpu") || fields[0] == "cpu" {
+				continue
+			}
+
+			// user nice system idle iowait irq softirq steal, the guest
+			// times are already counted in user and nice
+			rates := r.rates(fields[0], parseCounters(fields[1:9]), uptime)
+			total := 0.0
+			for _, rate := range rates {
+				total += rate
+			}
+			if total == 0 {
+				total = 1
+			}
+			percent := func(rates ...float64) text2table.Cell {
+				sum := 0.0
+				for _, rate := range rates {
+					sum += rate
+				}
+				return numberCell(sum/total*100, text2table.UnitPercent)
+			}
+
+			user, nice, system, idle, iowait, irq, softirq, steal :=
+				rates[0], rates[1], rates[2], rates[3], rates[4], rates[5], rates[6], rates[7]
+			b.add(
+				textCell(fields[0]),
+				percent(user, nice, system, irq, softirq),
+				percent(user, nice),
+				percent(system, irq, softirq),
+				percent(iowait),
+				percent(steal),
+				percent(idle),
+			)
+		}
+		r.done(uptime)
+
+		return b.table(0), nil
+	}
+}
+
+// diskColumns are the columns of the table of disks, in rates per second.
+var diskColumns = []text2table.Column{
+	{Name: "DEVICE", Type: text2table.Identifier},
+	{Name: "READS/s", Type: text2table.Numeric},
+	{Name: "WRITES/s", Type: text2table.Numeric},
+	{Name: "READ/s", Type: text2table.Numeric, Unit: text2table.UnitBytes},
+	{Name: "WRITTEN/s", Type: text2table.Numeric, Unit: text2table.UnitBytes},
+	{Name: "%UTIL", Type: text2table.Numeric, Unit: text2table.UnitPercent},
+	{Name: "IN FLIGHT", Type: text2table.Numeric},
+}
+
+// newDiskCollector returns a collector of the I/O of each disk and partition
+// from /proc/diskstats, leaving out the ones that have never been used like
+// most loop devices.
+func newDiskCollector(root string) func() (*text2table.Table, error) {
+	var r counterRates
+	return func() (*text2table.Table, error) {
+
+		uptime, err := readUptime(root)
+		if err != nil {
+			return nil, err
+		}
+		lines, err := readLines(filepath.Join(root, "diskstats"))
+		if err != nil {
+			return nil, err
+		}
+
+		b := newTableBuilder(diskColumns...)
+		for _, line := range lines {
+			// major minor name, then reads merged sectors ms, writes
+			// merged sectors ms, in flight, ms doing I/O
+			fields := strings.Fields(line)
+			if len(fields) < 13 {
+				continue
+			}
+			counters := parseCounters(fields[3:13])
+			if counters[0] == 0 && counters[4] == 0 {
+				continue
+			}
+
+			rates := r.rates(fields[2], counters, uptime)
+			b.add(
+				textCell(fields[2]),
+				numberCell(rates[0], text2table.UnitNone),
+				numberCell(rates[4], text2table.UnitNone),
+				numberCell(rates[2]*sectorSize, text2table.UnitBytes),
+				numberCell(rates[6]*sectorSize, text2table.UnitBytes),
+				numberCell(rates[9]/1000*100, text2table.UnitPercent),
+				numberCell(float64(counters[8]), text2table.UnitNone),
+			)
+		}
+		r.done(uptime)
+
+		return b.table(0), nil
+	}
+}
+
+// netColumns are the columns of the table of network interfaces, in rates
+// per second.
+var netColumns = []text2table.Column{
+	{Name: "INTERFACE", Type: text2table.Identifier},
+	{Name: "RX/s", Type: text2table.Numeric, Unit: text2table.UnitBytes},
+	{Name: "TX/s", Type: text2table.Numeric, Unit: text2table.UnitBytes},
+	{Name: "RX PACKETS/s", Type: text2table.Numeric},
+	{Name: "TX PACKETS/s", Type: text2table.Numeric},
+	{Name: "ERRORS/s", Type: text2table.Numeric},
+	{Name: "DROPS/s", Type: text2table.Numeric},
+}
+
+// newNetCollector returns a collector of the traffic of each network
+// interface from /proc/net/dev.
+func newNetCollector(root string) func() (*text2table.Table, error) {
+	var r counterRates
+	return func() (*text2table.Table, error) {
+
+		uptime, err := readUptime(root)
+		if err != nil {
+			return nil, err
+		}
+		lines, err := readLines(filepath.Join(root, "net", "dev"))
+		if err != nil {
+			return nil, err
+		}
+
+		b := newTableBuilder(netColumns...)
+		for _, line := range lines {
+			// the name is followed by a colon that may not be followed by
+			// a space, then eight counters received and eight sent, the
+			// headers have a bar instead
+			i := strings.IndexByte(line, ':')
+			if i < 0 {
+				continue
+			}
+			fields := strings.Fields(line[i+1:])
+			if len(fields) < 16 {
+				continue
+			}
+			name := strings.TrimSpace(line[:i])
+
+			// bytes packets errs drop of each direction
+			counters := parseCounters(append(fields[0:4:4], fields[8:12]...))
+			rates := r.rates(name, counters, uptime)
+			b.add(
+				textCell(name),
+				numberCell(rates[0], text2table.UnitBytes),
+				numberCell(rates[4], text2table.UnitBytes),
+				numberCell(rates[1], text2table.UnitNone),
+				numberCell(rates[5], text2table.UnitNone),
+				numberCell(rates[2]+rates[6], text2table.UnitNone),
+				numberCell(rates[3]+rates[7], text2table.UnitNone),
+			)
+		}
+		r.done(uptime)
+
+		return b.table(0), nil
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cove/oview/pkg/text2table"
+)
+
+func TestSystemCollectors(t *testing.T) {
+	tests := []struct {
+		name    string
+		collect func(root string) func() (*text2table.Table, error)
+		header  []string
+		// rows since boot, then since the first sample
+		rows [2][][]string
+	}{
+		{
+			name:    "cpu",
+			collect: newCPUCollector,
+			header:  []string{"CPU", "%BUSY", "%USER", "%SYSTEM", "%IOWAIT", "%STEAL", "%IDLE"},
+			rows: [2][][]string{
+				{
+					{"cpu0", "20%", "10%", "10%", "5%", "0%", "75%"},
+					{"cpu1", "30%", "25%", "5%", "0%", "0%", "70%"},
+				},
+				{
+					{"cpu0", "50%", "50%", "0%", "0%", "0%", "50%"},
+					{"cpu1", "20%", "10%", "10%", "0%", "0%", "80%"},
+				},
+			},
+		},
+		{
+			name:    "disk",
+			collect: newDiskCollector,
+			header:  []string{"DEVICE", "READS/s", "WRITES/s", "READ/s", "WRITTEN/s", "%UTIL", "IN FLIGHT"},
+			rows: [2][][]string{
+				{
+					{"sda", "10", "5", "1000.0 KiB", "500.0 KiB", "0.4%", "0"},
+					{"sda1", "9", "5", "900.0 KiB", "500.0 KiB", "0.39%", "0"},
+				},
+				{
+					{"sda", "100", "25", "1.0 MiB", "512.0 KiB", "50%", "2"},
+					{"sda1", "50", "25", "512.0 KiB", "512.0 KiB", "50%", "2"},
+				},
+			},
+		},
+		{
+			name:    "net",
+			collect: newNetCollector,
+			header:  []string{"INTERFACE", "RX/s", "TX/s", "RX PACKETS/s", "TX PACKETS/s", "ERRORS/s", "DROPS/s"},
+			rows: [2][][]string{
+				{
+					{"lo", "1.0 KiB", "1.0 KiB", "1", "1", "0", "0"},
+					{"eth0", "2.0 MiB", "1000.0 KiB", "2000", "1000", "0.01", "0.03"},
+				},
+				{
+					{"lo", "0 B", "0 B", "0", "0", "0", "0"},
+					{"eth0", "2.0 MiB", "1.0 MiB", "2000", "1000", "0", "1"},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the collector reads the same path, linked to each snapshot
+			dir, err := ioutil.TempDir("", "oview")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			root := filepath.Join(dir, "proc")
+			collect := tt.collect(root)
+			for i, want := range tt.rows {
+				snapshot, _ := filepath.Abs(fmt.Sprintf("testdata/proc/t%d", i))
+				os.Remove(root)
+				if err := os.Symlink(snapshot, root); err != nil {
+					t.Fatal(err)
+				}
+				table, err := collect()
+				if err != nil {
+					t.Fatalf("sample %d: error = %v", i, err)
+				}
+				if got := table.Header(); !reflect.DeepEqual(got, tt.header) {
+					t.Errorf("sample %d: header = %q, want %q", i, got, tt.header)
+				}
+				if got := cellTexts(table); !reflect.DeepEqual(got, want) {
+					t.Errorf("sample %d: rows =\n%q\nwant\n%q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCounterRates(t *testing.T) {
+	var r counterRates
+	if got := r.rates("a", []uint64{100, 50}, 10); !reflect.DeepEqual(got, []float64{10, 5}) {
+		t.Errorf("rates() since boot = %v", got)
+	}
+	r.done(10)
+	// the second counter was reset
+	if got := r.rates("a", []uint64{120, 4}, 12); !reflect.DeepEqual(got, []float64{10, 2}) {
+		t.Errorf("rates() since the last sample = %v", got)
+	}
+	if got := r.rates("b", []uint64{24}, 12); !reflect.DeepEqual(got, []float64{2}) {
+		t.Errorf("rates() of a new key = %v", got)
+	}
+}
+
+// cellTexts returns the text of the cells of each row of the table.
+func cellTexts(table *text2table.Table) [][]string {
+	var rows [][]string
+	for _, row := range table.Rows {
+		var cells []string
+		for _, cell := range row.Cells {
+			cells = append(cells, cell.Text)
+		}
+		rows = append(rows, cells)
+	}
+	return rows
+}
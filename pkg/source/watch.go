@@ -0,0 +1,84 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDelay is how long a file is left alone after it changes before it's
+// read, so a file written in several parts is read once.
+const watchDelay = 50 * time.Millisecond
+
+// watchFile returns a channel that receives when the file at path is
+// written or created, including by renaming another file over it like
+// tools that write atomically and log rotation do. The directory is watched
+// rather than the file, so the watch carries on when the file is replaced.
+// It stops when ctx is done.
+func watchFile(ctx context.Context, path string) (<-chan struct{}, error) {
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(filepath.Dir(abs)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	changed := make(chan struct{}, 1)
+	go func() {
+		defer watcher.Close()
+		var delay <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(ev.Name) == abs && ev.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+					delay = time.After(watchDelay)
+				}
+
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				// changes may have been missed, read it again to be sure
+				delay = time.After(watchDelay)
+
+			case <-delay:
+				delay = nil
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return changed, nil
+}
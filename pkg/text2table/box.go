@@ -0,0 +1,228 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// verticals are the characters separating the cells of a framed table.
+const verticals = "|│┃║┆┊"
+
+// borders are the characters border lines are drawn with, where the ones
+// in rules draw the horizontal lines.
+const (
+	borders = "+-=:|│┃║┆┊─━═┄┈" +
+		"┌┐└┘├┤┬┴┼┏┓┗┛┣┫┳┻╋┍┑┕┙┝┥┯┷┿┎┒┖┚┠┨┰┸╂" +
+		"╔╗╚╝╠╣╦╩╬╒╕╘╛╞╡╤╧╪╓╖╙╜╟╢╥╨╫╭╮╯╰"
+	rules = "-=─━═┄┈"
+)
+
+// readBox parses a table framed by border lines and cell separators, like
+// the ones printed by mysql, psql and `sqlite3 -box`, or a Markdown table.
+// The lines above the first rule under the header are joined into the
+// header, so titles can span lines. When rules separate the rows too, the
+// lines between two rules are joined into one row.
+func readBox(data []byte) ([]string, [][]string, error) {
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimSuffix(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	// the table is the first run of borders and rows, which skips anything
+	// printed before or after it like "(3 rows)" from psql
+	var segments [][][]string
+	var segment [][]string
+	started := false
+	for i, line := range lines {
+		border, row := isBorder(lines, i), isBoxRow(lines, i)
+		if !border && !row {
+			if started {
+				break
+			}
+			continue
+		}
+		started = true
+
+		if border {
+			if segment != nil {
+				segments = append(segments, segment)
+				segment = nil
+			}
+			continue
+		}
+		segment = append(segment, splitCells(line))
+	}
+	if segment != nil {
+		segments = append(segments, segment)
+	}
+	if len(segments) == 0 {
+		return nil, nil, nil
+	}
+
+	// without a rule under it the header is the first line
+	if len(segments) == 1 {
+		segments = [][][]string{segments[0][:1], segments[0][1:]}
+	}
+
+	header := joinCells(segments[0])
+	var table [][]string
+	for _, segment := range segments[1:] {
+		if len(segments) > 2 {
+			segment = [][]string{joinCells(segment)}
+		}
+		for _, cells := range segment {
+			if row := fitRow(cells, len(header), " | "); row != nil {
+				table = append(table, row)
+			}
+		}
+	}
+
+	return header, table, nil
+}
+
+// joinCells joins the cells of lines that make up one row, column by column.
+func joinCells(lines [][]string) []string {
+	var row []string
+	for _, cells := range lines {
+		for i, cell := range cells {
+			if i >= len(row) {
+				row = append(row, cell)
+			} else if cell != "" {
+				row[i] = strings.TrimSpace(row[i] + " " + cell)
+			}
+		}
+	}
+	return row
+}
+
+// splitCells splits a row of a framed table into its cells, dropping the
+// outer frame. Markdown cells can hold a "|" escaped as "\|".
+func splitCells(line string) []string {
+
+	line = strings.Replace(strings.TrimSpace(line), `\|`, "\x00", -1)
+	for _, r := range verticals {
+		if strings.HasPrefix(line, string(r)) {
+			line = line[len(string(r)):]
+		}
+		if strings.HasSuffix(line, string(r)) {
+			line = line[:len(line)-len(string(r))]
+		}
+	}
+
+	var cells []string
+	start := 0
+	for i, r := range line {
+		if strings.ContainsRune(verticals, r) {
+			cells = append(cells, line[start:i])
+			start = i + len(string(r))
+		}
+	}
+	cells = append(cells, line[start:])
+
+	for i, cell := range cells {
+		cells[i] = strings.TrimSpace(strings.Replace(cell, "\x00", "|", -1))
+	}
+	return cells
+}
+
+// isBorder reports whether the i'th line is drawn with nothing but border
+// characters, like "+----+----+" or "|:---|---:|". One with rules too short
+// to tell it from text, like "+-+", has to be shaped like a border of a row
+// next to it.
+func isBorder(lines []string, i int) bool {
+	n := 0
+	for _, r := range lines[i] {
+		if r == ' ' || r == '\t' {
+			continue
+		}
+		if !strings.ContainsRune(borders, r) {
+			return false
+		}
+		if strings.ContainsRune(rules, r) {
+			n++
+		}
+	}
+	if n >= 3 {
+		return true
+	}
+	return n > 0 && ((i > 0 && fitsRow(lines[i], lines[i-1])) ||
+		(i+1 < len(lines) && fitsRow(lines[i], lines[i+1])))
+}
+
+// fitsRow reports whether a border starts and ends with a corner or junction
+// and has them where the row has its verticals.
+func fitsRow(border, row string) bool {
+	b, r := []rune(border), []rune(row)
+	var joints []int
+	for p, c := range b {
+		if c != ' ' && c != '\t' && c != ':' && !strings.ContainsRune(rules, c) {
+			joints = append(joints, p)
+		}
+	}
+	trimmed := []rune(strings.TrimSpace(border))
+	if len(joints) < 2 || len(trimmed) == 0 ||
+		strings.ContainsRune(rules, trimmed[0]) || strings.ContainsRune(rules, trimmed[len(trimmed)-1]) {
+		return false
+	}
+
+	var bars []int
+	for p, c := range r {
+		if strings.ContainsRune(verticals, c) {
+			bars = append(bars, p)
+		}
+	}
+	if len(bars) != len(joints) {
+		return false
+	}
+	for k := range joints {
+		if joints[k] != bars[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// isBoxRow reports whether the i'th line is a row of cells separated by
+// verticals.
+func isBoxRow(lines []string, i int) bool {
+	return strings.ContainsAny(lines[i], verticals) && !isBorder(lines, i)
+}
+
+// looksLikeBox reports whether the input has a framed table near its start,
+// that is a border line next to a row.
+func looksLikeBox(data []byte) bool {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for i := 0; i < maxPreamble && scanner.Scan(); i++ {
+		lines = append(lines, scanner.Text())
+	}
+	for i := range lines {
+		if !isBorder(lines, i) {
+			continue
+		}
+		if (i > 0 && isBoxRow(lines, i-1)) || (i+1 < len(lines) && isBoxRow(lines, i+1)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseBox(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		header []string
+		table  [][]string
+	}{
+		{
+			name:   "psql",
+			file:   "testdata/psql.txt",
+			header: []string{"pid", "usename", "state", "query_ms"},
+			table: [][]string{
+				{"4211", "app", "active", "12.5"},
+				{"4290", "app", "idle", "0.0"},
+				{"4302", "reporter", "active", "3104.2"},
+			},
+		},
+		{
+			name:   "mysql",
+			file:   "testdata/mysql.txt",
+			header: []string{"id", "name", "status", "latency_ms"},
+			table: [][]string{
+				{"1", "api-gateway", "up", "12"},
+				{"2", "billing", "down", "NULL"},
+				{"3", "search", "up", "48"},
+			},
+		},
+		{
+			name:   "sqlite3 -box with a two line header",
+			file:   "testdata/sqlite-box.txt",
+			header: []string{"host", "disk", "used (GiB)"},
+			table: [][]string{
+				{"web1", "/dev/sda1", "41.2"},
+				{"web2", "/dev/sda1", "38.9"},
+				{"db1", "/dev/nvme0", "402.7"},
+			},
+		},
+		{
+			name:   "markdown",
+			file:   "testdata/report.md",
+			header: []string{"queue", "depth", "owner", "notes"},
+			table: [][]string{
+				{"email", "120", "comms", "retry | backoff"},
+				{"sms", "4", "comms", ""},
+				{"webhooks", "0", "platform", "paused"},
+			},
+		},
+		{
+			name:   "narrow",
+			file:   "testdata/narrow-box.txt",
+			header: []string{"a", "b"},
+			table:  [][]string{{"1", "2"}, {"3", "4"}},
+		},
+		{
+			name:   "grid with multi-line rows",
+			file:   "testdata/grid.txt",
+			header: []string{"service", "endpoints"},
+			table: [][]string{
+				{"api", "/v1/users /v1/teams"},
+				{"auth", "/login"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fd, err := os.Open(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer fd.Close()
+
+			header, table, err := NewTable(fd)
+			if err != nil {
+				t.Fatalf("NewTable() error = %v", err)
+			}
+			if !reflect.DeepEqual(header, tt.header) {
+				t.Errorf("NewTable() header = %q, want %q", header, tt.header)
+			}
+			if !reflect.DeepEqual(table, tt.table) {
+				t.Errorf("NewTable() table = %q, want %q", table, tt.table)
+			}
+		})
+	}
+}
+
+func Test_isBorder(t *testing.T) {
+	tests := []struct {
+		lines []string
+		i     int
+		want  bool
+	}{
+		{[]string{"+----+------+"}, 0, true},
+		{[]string{"------+----------"}, 0, true},
+		{[]string{"|:---|---:|"}, 0, true},
+		{[]string{"├──────┼────┤"}, 0, true},
+		{[]string{"+====+"}, 0, true},
+		{[]string{"| id | name |"}, 0, false},
+		{[]string{"--"}, 0, false},
+		{[]string{"---- not a border"}, 0, false},
+		{[]string{""}, 0, false},
+		{[]string{"+--+", "|a |", "+--+"}, 0, true},
+		{[]string{"+--+", "|a |", "+--+"}, 2, true},
+		{[]string{"+-+", "|a|"}, 0, true},
+		{[]string{"+-+", "| a |"}, 0, false},
+		{[]string{"-+", "a|"}, 0, false},
+		{[]string{"+-+"}, 0, false},
+	}
+	for _, tt := range tests {
+		if got := isBorder(tt.lines, tt.i); got != tt.want {
+			t.Errorf("isBorder(%q, %d) = %v, want %v", tt.lines, tt.i, got, tt.want)
+		}
+	}
+}
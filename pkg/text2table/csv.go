@@ -0,0 +1,100 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+// readCSV parses comma separated values, where fields may be quoted to hold
+// commas, doubled quotes or line breaks. Other separators like ';' or ':'
+// are read the same way, but without being strict about quotes.
+func readCSV(data []byte, opts Options, comma rune) ([]string, [][]string, error) {
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = comma
+	r.Comment = opts.Comment
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.LazyQuotes = comma != ','
+
+	var table [][]string
+	var header []string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if header == nil {
+			for _, v := range record {
+				header = append(header, strings.TrimSpace(v))
+			}
+			continue
+		}
+
+		if row := fitRow(record, len(header), string(comma)); row != nil {
+			table = append(table, row)
+		}
+	}
+
+	return header, table, nil
+}
+
+// readTSV parses tab separated values. Tabs and line breaks can't appear in
+// values, so quotes are only removed from fields that are entirely quoted.
+func readTSV(data []byte, opts Options) ([]string, [][]string, error) {
+
+	var table [][]string
+	var header []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" || (opts.Comment != 0 && strings.HasPrefix(line, string(opts.Comment))) {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		for i, v := range fields {
+			if len(v) > 1 && strings.HasPrefix(v, `"`) && strings.HasSuffix(v, `"`) {
+				fields[i] = strings.Replace(v[1:len(v)-1], `""`, `"`, -1)
+			}
+		}
+
+		if header == nil {
+			for _, v := range fields {
+				header = append(header, strings.TrimSpace(v))
+			}
+			continue
+		}
+
+		if row := fitRow(fields, len(header), "\t"); row != nil {
+			table = append(table, row)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return header, table, nil
+}
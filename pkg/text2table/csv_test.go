@@ -0,0 +1,73 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		opts   Options
+		header []string
+		table  [][]string
+	}{
+		{
+			name:   "csv export",
+			file:   "testdata/export.csv",
+			opts:   Options{Comment: '#'},
+			header: []string{"id", "name", "notes", "amount"},
+			table: [][]string{
+				{"1", "Smith, Jane", `said "hi"`, "12.50"},
+				{"2", "Bob", "line one\nline two", "7"},
+				{"3", "O'Brien", "", "0.25"},
+			},
+		},
+		{
+			name:   "tsv",
+			file:   "testdata/hosts.tsv",
+			opts:   Options{Format: FormatTSV},
+			header: []string{"host", "load", "comment"},
+			table: [][]string{
+				{"web-1", "0.52", `"quoted" text`},
+				{"db-1", "1.75", "nightly backup"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fd, err := os.Open(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer fd.Close()
+
+			header, table, err := Parse(fd, tt.opts)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(header, tt.header) {
+				t.Errorf("Parse() header = %q, want %q", header, tt.header)
+			}
+			if !reflect.DeepEqual(table, tt.table) {
+				t.Errorf("Parse() table = %q, want %q", table, tt.table)
+			}
+		})
+	}
+}
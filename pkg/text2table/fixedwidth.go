@@ -0,0 +1,445 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"strings"
+	"unicode"
+)
+
+// span is a half open [start, end) range of rune offsets in a line.
+type span struct {
+	start, end int
+}
+
+// fixedGroup is a range of offsets holding one or more columns. Groups are
+// separated by offsets that are blank on every line, so no value can cross
+// from one group into the next. Columns that share a group (because some
+// value overlaps the gap between their titles) are split line by line at
+// the whitespace closest to that gap.
+type fixedGroup struct {
+	span
+	columns []int
+}
+
+// fixedLayout describes a fixed-width table such as the output of
+// `docker ps`, `df -h` or `kubectl get`, where columns are aligned on
+// character offsets rather than delimited by a separator.
+type fixedLayout struct {
+	names  []string
+	titles []span
+	groups []fixedGroup
+}
+
+// newFixedLayout infers the column boundaries of a fixed-width table from the
+// offsets of the header's titles and the whitespace that is consistent
+// across all of the lines. It returns nil when the lines don't look
+// columnar, in which case the caller should fall back to splitting fields.
+func newFixedLayout(header string, lines []string) *fixedLayout {
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	rows := make([][]rune, 0, len(lines)+1)
+	rows = append(rows, expandTabs(header))
+	for _, line := range lines {
+		rows = append(rows, expandTabs(line))
+	}
+
+	width := 0
+	for _, row := range rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+
+	// offsets that are blank on every line separate the blocks of text
+	var blocks []span
+	start := -1
+	for p := 0; p <= width; p++ {
+		blank := true
+		if p < width {
+			for _, row := range rows {
+				if p < len(row) && !unicode.IsSpace(row[p]) {
+					blank = false
+					break
+				}
+			}
+		}
+		if !blank && start < 0 {
+			start = p
+		} else if blank && start >= 0 {
+			blocks = append(blocks, span{start, p})
+			start = -1
+		}
+	}
+
+	titles := fieldSpans(rows[0])
+	if len(titles) < 2 {
+		return nil
+	}
+
+	// sort the titles into the blocks they sit in, joining titles that are
+	// only one space apart with values running through that space on most
+	// lines back into a single name (e.g. "CONTAINER ID" from `docker ps`),
+	// as well as ones joined by a symbol (e.g. "MEM USAGE / LIMIT") or whose
+	// values run on from the title before (e.g. "Local Address:Port" from ss)
+	type block struct {
+		span
+		titles []span
+	}
+	var placed []block
+	t := 0
+	for _, b := range blocks {
+		pb := block{span: b}
+		for t < len(titles) && titles[t].start < b.end {
+			n := len(pb.titles)
+			if n > 0 && titles[t].start-pb.titles[n-1].end == 1 &&
+				(isSpanned(rows[1:], titles[t].start-1) || isJoiner(rows[0], titles[t]) ||
+					isRunOn(rows[1:], rows[0], titles[t])) {
+				pb.titles[n-1].end = titles[t].end
+			} else {
+				pb.titles = append(pb.titles, titles[t])
+			}
+			t++
+		}
+		placed = append(placed, pb)
+	}
+
+	// a title word with no values under it or starting with a symbol that's
+	// only one space from the previous title is part of that title (e.g.
+	// "Mounted on" from df or "MEM USAGE / LIMIT" from docker stats)
+	for i := 1; i < len(placed); i++ {
+		b := placed[i]
+		joined := len(b.titles) > 0 && isJoiner(rows[0], b.titles[0])
+		if !joined && (len(b.titles) != 1 || !isEmptyColumn(rows[1:], b.span)) {
+			continue
+		}
+		j := i - 1
+		for j >= 0 && len(placed[j].titles) == 0 {
+			j--
+		}
+		if j < 0 {
+			continue
+		}
+		prev := &placed[j]
+		last := &prev.titles[len(prev.titles)-1]
+		if b.titles[0].start-last.end != 1 {
+			continue
+		}
+		last.end = b.titles[0].end
+		prev.titles = append(prev.titles, b.titles[1:]...)
+		prev.end = b.end
+		placed = append(placed[:j+1], placed[i+1:]...)
+		i = j
+	}
+
+	layout := &fixedLayout{}
+	for _, b := range placed {
+		if len(b.titles) == 0 {
+			continue
+		}
+		g := fixedGroup{span: b.span}
+		for _, title := range b.titles {
+			g.columns = append(g.columns, len(layout.titles))
+			layout.titles = append(layout.titles, title)
+			layout.names = append(layout.names, string(rows[0][title.start:title.end]))
+		}
+		layout.groups = append(layout.groups, g)
+	}
+	if len(layout.titles) < 2 {
+		return nil
+	}
+
+	// blocks without a title belong to the group on their left, unless
+	// they're under a right aligned column and the one on the left isn't
+	// left aligned, then they're the start of the group on their right
+	groups := layout.groups
+	g := 0
+	for _, b := range placed {
+		if len(b.titles) > 0 {
+			g++
+			continue
+		}
+		if g == 0 || g == len(groups) {
+			continue
+		}
+		left := layout.titles[groups[g-1].columns[len(groups[g-1].columns)-1]]
+		right := layout.titles[groups[g].columns[0]]
+		if b.start < groups[g].start && isRightAligned(rows[1:], right) && !isLeftAligned(rows[1:], left) {
+			groups[g].start = b.start
+		}
+	}
+
+	// every offset belongs to a group, the first column takes anything before
+	// it and the last column anything after it
+	groups[0].start = 0
+	for i := 1; i < len(groups); i++ {
+		groups[i-1].end = groups[i].start
+	}
+	groups[len(groups)-1].end = width
+
+	// the values have to line up with their titles, and a title only one
+	// space after the previous one needs that on most lines, otherwise the
+	// space is just one between words (e.g. "id name msg" over prose)
+	for i, title := range layout.titles {
+		if isEmptyColumn(rows[1:], title) {
+			continue
+		}
+		n := linedUp(rows[1:], rows[0], title)
+		if n == 0 || (i > 0 && title.start-layout.titles[i-1].end == 1 && n*2 <= len(rows)-1) {
+			return nil
+		}
+	}
+
+	// only columnar if every line can be split without cutting into a value
+	for _, row := range rows[1:] {
+		if _, ok := layout.split(row); !ok {
+			return nil
+		}
+	}
+
+	return layout
+}
+
+// header returns the column names of the layout.
+func (l *fixedLayout) header() []string {
+	return append([]string(nil), l.names...)
+}
+
+// rows splits the lines into fields, skipping blank lines.
+func (l *fixedLayout) rows(lines []string) [][]string {
+	var table [][]string
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields, ok := l.split(expandTabs(line))
+		if !ok {
+			continue
+		}
+		table = append(table, fields)
+	}
+	return table
+}
+
+// split cuts a line into one field per column. It fails if two columns that
+// share a group can't be separated at a whitespace.
+func (l *fixedLayout) split(row []rune) ([]string, bool) {
+
+	fields := make([]string, 0, len(l.titles))
+	for i, g := range l.groups {
+		end := g.end
+		if i == len(l.groups)-1 && len(row) > end {
+			// last column takes the rest of the line
+			end = len(row)
+		}
+
+		lo := g.start
+		for c := 0; c < len(g.columns)-1; c++ {
+			gapL := l.titles[g.columns[c]].end
+			gapR := l.titles[g.columns[c+1]].start
+
+			// prefer cuts that leave text on both sides, for lines that
+			// are shifted over by a wide value earlier on
+			cut, dist := -1, 0
+			for p := lo + 1; p < end; p++ {
+				if p < len(row) && !unicode.IsSpace(row[p]) {
+					continue
+				}
+				d := 0
+				if p < gapL {
+					d = gapL - p
+				} else if p >= gapR {
+					d = p - gapR + 1
+				}
+				if substr(row, lo, p) == "" || substr(row, p, end) == "" {
+					d += end
+				}
+				if cut < 0 || d < dist {
+					cut, dist = p, d
+				}
+			}
+			if cut < 0 {
+				return nil, false
+			}
+			fields = append(fields, substr(row, lo, cut))
+			lo = cut
+		}
+		fields = append(fields, substr(row, lo, end))
+	}
+
+	return fields, true
+}
+
+// fieldSpans returns the offsets of the whitespace separated fields in row.
+func fieldSpans(row []rune) []span {
+	var spans []span
+	start := -1
+	for p := 0; p <= len(row); p++ {
+		space := p == len(row) || unicode.IsSpace(row[p])
+		if !space && start < 0 {
+			start = p
+		} else if space && start >= 0 {
+			spans = append(spans, span{start, p})
+			start = -1
+		}
+	}
+	return spans
+}
+
+// isSpanned reports whether most of the rows have text at offset p.
+func isSpanned(rows [][]rune, p int) bool {
+	n := 0
+	for _, row := range rows {
+		if p < len(row) && !unicode.IsSpace(row[p]) {
+			n++
+		}
+	}
+	return n*2 > len(rows)
+}
+
+// isJoiner reports whether a title starts with a word that's a symbol like
+// "/", which joins it to the title before it rather than being a name.
+func isJoiner(header []rune, title span) bool {
+	word := strings.Fields(substr(header, title.start, title.end))
+	if len(word) == 0 {
+		return false
+	}
+	for _, r := range word[0] {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isEmptyColumn reports whether no row has any text in the given range.
+func isEmptyColumn(rows [][]rune, s span) bool {
+	for _, row := range rows {
+		if strings.TrimSpace(substr(row, s.start, s.end)) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// isLeftAligned reports whether values mostly start where their title does.
+func isLeftAligned(rows [][]rune, title span) bool {
+	left, right := alignment(rows, title)
+	return left > 0 && left >= right
+}
+
+// isRightAligned reports whether values mostly end where their title does.
+func isRightAligned(rows [][]rune, title span) bool {
+	left, right := alignment(rows, title)
+	return right > 0 && right > left
+}
+
+// alignment counts the rows whose values start at the start of the title
+// and the rows whose values end at the end of the title.
+func alignment(rows [][]rune, title span) (left int, right int) {
+	isText := func(row []rune, p int) bool {
+		return p >= 0 && p < len(row) && !unicode.IsSpace(row[p])
+	}
+	for _, row := range rows {
+		if isText(row, title.start) && !isText(row, title.start-1) {
+			left++
+		}
+		if isText(row, title.end-1) && !isText(row, title.end) {
+			right++
+		}
+	}
+	return left, right
+}
+
+// anchored counts the rows with the first symbol inside the title (e.g. the
+// colon of "MAJ:MIN" from lsblk) at the same offset, for values that line up
+// on it rather than on either end of the title.
+func anchored(rows [][]rune, header []rune, title span) int {
+	p := -1
+	for i := title.start + 1; i < title.end-1; i++ {
+		r := header[i]
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r) {
+			p = i
+			break
+		}
+	}
+	if p < 0 {
+		return 0
+	}
+	n := 0
+	for _, row := range rows {
+		if p < len(row) && row[p] == header[p] {
+			n++
+		}
+	}
+	return n
+}
+
+// linedUp counts the rows whose values line up with the title, on its start,
+// its end or a symbol in it, whichever the most of them do.
+func linedUp(rows [][]rune, header []rune, title span) int {
+	left, right := alignment(rows, title)
+	n := anchored(rows, header, title)
+	if left > n {
+		n = left
+	}
+	if right > n {
+		n = right
+	}
+	return n
+}
+
+// isRunOn reports whether most values line up on a symbol in the title but
+// don't start where it does, so the title before it is part of its name.
+func isRunOn(rows [][]rune, header []rune, title span) bool {
+	left, _ := alignment(rows, title)
+	return anchored(rows, header, title)*2 > len(rows) && left*2 <= len(rows)
+}
+
+// substr returns the trimmed text of row between the offsets start and end,
+// clamped to the length of the row.
+func substr(row []rune, start, end int) string {
+	if end > len(row) {
+		end = len(row)
+	}
+	if start >= end {
+		return ""
+	}
+	return strings.TrimSpace(string(row[start:end]))
+}
+
+// expandTabs converts a line to runes with tabs expanded to 8 column stops.
+func expandTabs(line string) []rune {
+	if !strings.Contains(line, "\t") {
+		return []rune(line)
+	}
+	var row []rune
+	for _, r := range line {
+		if r == '\t' {
+			for {
+				row = append(row, ' ')
+				if len(row)%8 == 0 {
+					break
+				}
+			}
+			continue
+		}
+		row = append(row, r)
+	}
+	return row
+}
@@ -0,0 +1,154 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewTableFixedWidth(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		header []string
+		rows   int
+		row    int
+		want   []string
+	}{
+		{
+			name:   "docker ps",
+			file:   "testdata/docker-ps.txt",
+			header: []string{"CONTAINER ID", "IMAGE", "COMMAND", "CREATED", "STATUS", "PORTS", "NAMES"},
+			rows:   4,
+			row:    2,
+			want:   []string{"f0e1d2c3b4a5", "redis:7-alpine", `"redis-server"`, "3 days ago", "Exited (0) 5 minutes ago", "", "cache"},
+		},
+		{
+			name:   "df -h",
+			file:   "testdata/df-h.txt",
+			header: []string{"Filesystem", "Size", "Used", "Avail", "Use%", "Mounted on"},
+			rows:   8,
+			row:    6,
+			want:   []string{"//nas/share", "3.6T", "1.2T", "2.4T", "34%", "/mnt/nas share"},
+		},
+		{
+			name:   "kubectl get pods",
+			file:   "testdata/kubectl-get-pods.txt",
+			header: []string{"NAME", "READY", "STATUS", "RESTARTS", "AGE"},
+			rows:   6,
+			row:    4,
+			want:   []string{"metrics-server-7746886d4f-zt9qw", "0/1", "CrashLoopBackOff", "214 (4m ago)", "12d"},
+		},
+		{
+			name:   "ss -tan",
+			file:   "testdata/ss-tan.txt",
+			header: []string{"State", "Recv-Q", "Send-Q", "Local Address:Port", "Peer Address:Port", "Process"},
+			rows:   6,
+			row:    5,
+			want:   []string{"TIME-WAIT", "0", "0", "10.0.2.15:41876", "151.101.1.69:443", ""},
+		},
+		{
+			name:   "lsblk",
+			file:   "testdata/lsblk.txt",
+			header: []string{"NAME", "MAJ:MIN", "RM", "SIZE", "RO", "TYPE", "MOUNTPOINTS"},
+			rows:   8,
+			row:    3,
+			want:   []string{"├─nvme0n1p1", "259:1", "0", "1G", "0", "part", "/boot/efi"},
+		},
+		{
+			name:   "ps aux",
+			file:   "testdata/ps-aux-osx.txt",
+			header: []string{"USER", "PID", "%CPU", "%MEM", "VSZ", "RSS", "TT", "STAT", "STARTED", "TIME", "COMMAND"},
+			rows:   374,
+			row:    1,
+			want:   []string{"bach", "404", "3.1", "2.1", "5555400", "345932", "??", "S", "4:50PM", "53:54.36", "/Applications/Google Chrome.app/Contents/MacOS/Google Chrome -psn_0_86037"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fd, err := os.Open(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer fd.Close()
+
+			header, table, err := NewTable(fd)
+			if err != nil {
+				t.Fatalf("NewTable() error = %v", err)
+			}
+			if !reflect.DeepEqual(header, tt.header) {
+				t.Errorf("NewTable() header = %q, want %q", header, tt.header)
+			}
+			if len(table) != tt.rows {
+				t.Fatalf("NewTable() rows = %v, want %v", len(table), tt.rows)
+			}
+			if !reflect.DeepEqual(table[tt.row], tt.want) {
+				t.Errorf("NewTable() row %v = %q, want %q", tt.row, table[tt.row], tt.want)
+			}
+		})
+	}
+}
+
+func Test_newFixedLayout(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  [][]string
+	}{
+		{
+			name:  "right aligned values wider than title",
+			input: "NAME  N\nfoo   1\nbar 12345",
+			want:  [][]string{{"foo", "1"}, {"bar", "12345"}},
+		},
+		{
+			name:  "empty cells",
+			input: "A     B     C\n1           3\n4     5     6",
+			want:  [][]string{{"1", "", "3"}, {"4", "5", "6"}},
+		},
+		{
+			name:  "ragged lines are not columnar",
+			input: "name a b\nlongvalue 3 4",
+			want:  nil,
+		},
+		{
+			name:  "words under single spaced titles are not columnar",
+			input: "id name msg\n1 alice hello world\n2 bob hi there",
+			want:  nil,
+		},
+		{
+			name:  "values not lined up with titles are not columnar",
+			input: "PID CMD\n1 a b c\n2 d",
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := strings.Split(tt.input, "\n")
+			layout := newFixedLayout(lines[0], lines[1:])
+			if layout == nil {
+				if tt.want != nil {
+					t.Fatalf("newFixedLayout() = nil, want %q", tt.want)
+				}
+				return
+			}
+			if got := layout.rows(lines[1:]); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newFixedLayout().rows() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"regexp"
+	"strings"
+)
+
+// clearScreen matches the escape sequences that clear the terminal, which
+// commands like `docker stats` print before each update.
+var clearScreen = regexp.MustCompile(`\x1b\[[23]J|\x1bc`)
+
+// escapes matches the other terminal escape sequences such as colors and
+// cursor movement, which are dropped.
+var escapes = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]|\x1b[()][0-9A-Za-z]`)
+
+// FrameSplitter splits the output of a command that keeps running, like
+// `vmstat 1` or `top -b`, into the frames it prints one after another. A
+// frame ends when the terminal is cleared, at a delimiter line if one is
+// set, or otherwise at a blank line after a table or a repeat of a header.
+type FrameSplitter struct {
+	// Delimiter is a line that separates frames, when set blank lines and
+	// repeated headers don't
+	Delimiter string
+
+	lines   []string
+	headers map[string]bool
+	pending int
+	header  string
+}
+
+// NewFrameSplitter returns a FrameSplitter that splits frames at the given
+// delimiter line, or finds where they end by itself if it's empty.
+func NewFrameSplitter(delimiter string) *FrameSplitter {
+	return &FrameSplitter{
+		Delimiter: strings.TrimSpace(delimiter),
+		headers:   map[string]bool{},
+		pending:   -1,
+	}
+}
+
+// Add adds a line of output, returning the frames it completes if any.
+func (s *FrameSplitter) Add(line string) [][]string {
+
+	var frames [][]string
+	parts := clearScreen.Split(strings.TrimSuffix(line, "\r"), -1)
+	for i, part := range parts {
+		if i > 0 {
+			if frame := s.Flush(); frame != nil {
+				frames = append(frames, frame)
+			}
+		}
+		part = escapes.ReplaceAllString(part, "")
+		if part == "" && len(parts) > 1 {
+			continue
+		}
+		if frame := s.addLine(part); frame != nil {
+			frames = append(frames, frame)
+		}
+	}
+
+	return frames
+}
+
+// Flush returns the lines added since the last frame as a frame, or nil if
+// there aren't any.
+func (s *FrameSplitter) Flush() []string {
+	return s.cut(len(s.lines))
+}
+
+func (s *FrameSplitter) addLine(line string) []string {
+
+	trimmed := strings.TrimSpace(line)
+	if s.Delimiter != "" {
+		if trimmed == s.Delimiter {
+			return s.Flush()
+		}
+		s.lines = append(s.lines, line)
+		return nil
+	}
+
+	if trimmed == "" {
+		if s.pending < 0 && s.hasTable() {
+			s.pending = len(s.lines)
+		}
+		s.lines = append(s.lines, line)
+		return nil
+	}
+
+	var frame []string
+	header := IsTableHeader(line)
+	switch {
+	case header && s.headers[trimmed]:
+		// a repeated header starts a new frame, which takes along anything
+		// printed after the last table like the titles vmstat prints
+		// above its header
+		at := s.pending
+		if at < 0 {
+			_, at = FindTable(s.lines)
+		}
+		frame = s.cut(at)
+
+	case s.pending >= 0 && !header:
+		// a blank line after a table ends the frame unless another table
+		// follows, as in the reports from iostat
+		frame = s.cut(s.pending)
+	}
+
+	s.pending = -1
+	if header {
+		s.headers[trimmed] = true
+		s.header = line
+	}
+	s.lines = append(s.lines, line)
+	return frame
+}
+
+// hasTable reports whether the lines so far hold a header with a row.
+func (s *FrameSplitter) hasTable() bool {
+	seen := false
+	for _, line := range s.lines {
+		trimmed := strings.TrimSpace(line)
+		if seen && trimmed != "" {
+			return true
+		}
+		seen = seen || s.headers[trimmed]
+	}
+	return false
+}
+
+// cut returns the lines before i as a frame, keeping the rest for the next
+// one. A frame without a header of its own, such as the rows printed by
+// `kubectl get -w` after the first, gets the last header that was seen.
+func (s *FrameSplitter) cut(i int) []string {
+
+	frame := s.lines[:i]
+	rest := append([]string(nil), s.lines[i:]...)
+
+	s.lines = nil
+	s.headers = map[string]bool{}
+	s.pending = -1
+	for _, line := range rest {
+		if IsTableHeader(line) {
+			s.headers[strings.TrimSpace(line)] = true
+		}
+		s.lines = append(s.lines, line)
+	}
+
+	empty, headed := true, false
+	for _, line := range frame {
+		empty = empty && strings.TrimSpace(line) == ""
+		headed = headed || IsTableHeader(line)
+	}
+	if empty {
+		return nil
+	}
+	if !headed && s.header != "" {
+		frame = append([]string{s.header}, frame...)
+	}
+	return frame
+}
@@ -0,0 +1,136 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"bufio"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFrameSplitter(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		delimiter string
+		header    []string
+		rows      []int
+		first     []string
+	}{
+		{
+			name:   "top -b",
+			file:   "testdata/top-b-stream.txt",
+			header: strings.Fields("PID USER PR NI VIRT RES SHR S %CPU %MEM TIME+ COMMAND"),
+			rows:   []int{5, 5},
+			first:  strings.Fields("4129 cove 20 0 4103260 412340 120304 S 6.2 2.5 5:12.34 firefox-esr"),
+		},
+		{
+			name:   "vmstat 1",
+			file:   "testdata/vmstat-stream.txt",
+			header: strings.Fields("r b swpd free buff cache si so bi bo in cs us sy id wa st"),
+			rows:   []int{2, 1},
+			first:  strings.Fields("2 0 0 1229640 402320 6322000 0 0 0 128 530 977 5 2 93 0 0"),
+		},
+		{
+			name:   "iostat 2",
+			file:   "testdata/iostat-stream.txt",
+			header: strings.Fields("Device tps kB_read/s kB_wrtn/s kB_dscd/s kB_read kB_wrtn kB_dscd"),
+			rows:   []int{3, 3},
+			first:  strings.Fields("loop0 0.00 0.00 0.00 0.00 17 0 0"),
+		},
+		{
+			name:   "docker stats",
+			file:   "testdata/docker-stats-stream.txt",
+			header: []string{"CONTAINER ID", "NAME", "CPU %", "MEM USAGE / LIMIT", "MEM %", "PIDS"},
+			rows:   []int{2, 2},
+			first:  []string{"4c01db0b339c", "web", "0.20%", "12.4MiB / 2GiB", "0.61%", "3"},
+		},
+		{
+			name:      "delimiter",
+			file:      "testdata/top-b-stream.txt",
+			delimiter: "MiB Swap:   2048.0 total,   2048.0 free,      0.0 used.   7090.1 avail Mem",
+			header:    strings.Fields("PID USER PR NI VIRT RES SHR S %CPU %MEM TIME+ COMMAND"),
+			rows:      []int{0, 5, 5},
+			first:     strings.Fields("4129 cove 20 0 4103260 412340 120304 S 6.2 2.5 5:12.34 firefox-esr"),
+		},
+		{
+			name:      "delimiter line",
+			file:      "testdata/delimited-stream.txt",
+			delimiter: "---",
+			header:    []string{"NAME", "READY", "STATUS"},
+			rows:      []int{2, 1},
+			first:     []string{"web", "0/1", "Pending"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fd, err := os.Open(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer fd.Close()
+
+			var frames [][]string
+			s := NewFrameSplitter(tt.delimiter)
+			scanner := bufio.NewScanner(fd)
+			for scanner.Scan() {
+				frames = append(frames, s.Add(scanner.Text())...)
+			}
+			if frame := s.Flush(); frame != nil {
+				frames = append(frames, frame)
+			}
+
+			if len(frames) != len(tt.rows) {
+				t.Fatalf("got %d frames, want %d: %q", len(frames), len(tt.rows), frames)
+			}
+			for i, frame := range frames {
+				header, rows, err := Parse(strings.NewReader(strings.Join(frame, "\n")), Options{})
+				if err != nil {
+					t.Fatalf("frame %d: Parse() error = %v", i, err)
+				}
+				if len(rows) != tt.rows[i] {
+					t.Errorf("frame %d: got %d rows, want %d", i, len(rows), tt.rows[i])
+				}
+				if i == len(frames)-1 {
+					if !reflect.DeepEqual(header, tt.header) {
+						t.Errorf("frame %d: header = %q, want %q", i, header, tt.header)
+					}
+					if !reflect.DeepEqual(rows[0], tt.first) {
+						t.Errorf("frame %d: rows[0] = %q, want %q", i, rows[0], tt.first)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFrameSplitterHeaderless(t *testing.T) {
+	s := NewFrameSplitter("")
+	for _, line := range []string{"NAME      READY   STATUS    RESTARTS   AGE", "web-7d4b9   1/1     Running   0          3d"} {
+		s.Add(line)
+	}
+	s.Flush()
+
+	s.Add("web-7d4b9   0/1     Terminating   0          3d")
+	want := []string{"NAME      READY   STATUS    RESTARTS   AGE", "web-7d4b9   0/1     Terminating   0          3d"}
+	if frame := s.Flush(); !reflect.DeepEqual(frame, want) {
+		t.Errorf("Flush() = %q, want %q", frame, want)
+	}
+	if frame := s.Flush(); frame != nil {
+		t.Errorf("Flush() = %q, want nil", frame)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"strings"
+	"unicode"
+)
+
+// maxPreamble is how many lines into the input a header is looked for.
+const maxPreamble = 64
+
+// IsTableHeader reports whether a line looks like the header of a table:
+// mostly names rather than numbers, and not a ruler, a sentence or a summary
+// like "Tasks: 287 total, 1 running" from top.
+func IsTableHeader(s string) bool {
+
+	fields := strings.Fields(s)
+	if len(fields) < 2 {
+		return false
+	}
+
+	// rulers and group titles like "procs -----memory-----" from vmstat
+	if (strings.Count(s, "-")+strings.Count(s, "="))*2 > len(strings.TrimSpace(s)) {
+		return false
+	}
+
+	numeric, named := 0, 0
+	for _, f := range fields {
+		if strings.HasSuffix(f, ",") {
+			return false
+		}
+		if NewCell(f).Numeric {
+			numeric++
+		}
+		if strings.IndexFunc(f, unicode.IsLetter) >= 0 {
+			named++
+		}
+	}
+
+	return numeric*4 <= len(fields) && named*2 >= len(fields)
+}
+
+// FindTable returns the range of lines [start, end) holding the table, where
+// start is the header. Candidate headers are scored by the number of lines
+// following them that have a consistent number of fields, which skips the
+// preamble before a table (e.g. the summary from top) and picks the largest
+// table when there are several (e.g. iostat). The table ends at a blank
+// line, a repeat of the header, or the last line that fits the header.
+func FindTable(lines []string) (int, int) {
+
+	start, end, best := 0, len(lines), -1
+	for i := 0; i < len(lines) && i < maxPreamble; i++ {
+		if !IsTableHeader(lines[i]) {
+			continue
+		}
+
+		n := len(strings.Fields(lines[i]))
+		score, last := 0, i
+		for j := i + 1; j < len(lines); j++ {
+			line := strings.TrimSpace(lines[j])
+			if line == "" || line == strings.TrimSpace(lines[i]) {
+				break
+			}
+			if fields := len(strings.Fields(line)); fields*2 >= n {
+				score++
+				last = j
+			}
+		}
+
+		if score > best {
+			start, end, best = i, last+1, score
+		}
+	}
+
+	return start, end
+}
@@ -0,0 +1,126 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindTable(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		start int
+		end   int
+	}{
+		{
+			name:  "header first",
+			lines: []string{"NAME  SIZE", "a     1", "b     2"},
+			start: 0,
+			end:   3,
+		},
+		{
+			name:  "preamble and footer",
+			lines: []string{"Connected.", "", "NAME  SIZE", "a     1", "b     2", "", "2 rows"},
+			start: 2,
+			end:   5,
+		},
+		{
+			name:  "repeated header",
+			lines: []string{"NAME  SIZE", "a     1", "NAME  SIZE", "b     2"},
+			start: 0,
+			end:   2,
+		},
+		{
+			name:  "no header",
+			lines: []string{"1 2", "3 4"},
+			start: 0,
+			end:   2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := FindTable(tt.lines)
+			if start != tt.start || end != tt.end {
+				t.Errorf("FindTable() = %v, %v, want %v, %v", start, end, tt.start, tt.end)
+			}
+		})
+	}
+}
+
+func TestParseCorpus(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		header []string
+		rows   int
+		first  []string
+	}{
+		{
+			name:   "top -b",
+			file:   "testdata/top-b.txt",
+			header: strings.Fields("PID USER PR NI VIRT RES SHR S %CPU %MEM TIME+ COMMAND"),
+			rows:   5,
+			first:  strings.Fields("4129 cove 20 0 4103260 412340 120304 S 6.2 2.5 5:12.34 firefox"),
+		},
+		{
+			name:   "vmstat",
+			file:   "testdata/vmstat.txt",
+			header: strings.Fields("r b swpd free buff cache si so bi bo in cs us sy id wa st"),
+			rows:   3,
+			first:  strings.Fields("1 0 0 1232124 402312 6321940 0 0 26 54 301 612 3 1 96 0 0"),
+		},
+		{
+			name:   "iostat",
+			file:   "testdata/iostat.txt",
+			header: strings.Fields("Device tps kB_read/s kB_wrtn/s kB_dscd/s kB_read kB_wrtn kB_dscd"),
+			rows:   3,
+			first:  strings.Fields("loop0 0.00 0.00 0.00 0.00 17 0 0"),
+		},
+		{
+			name:   "free",
+			file:   "testdata/free.txt",
+			header: strings.Fields("name total used free shared buff/cache available"),
+			rows:   2,
+			first:  strings.Fields("Mem: 16318412 8318508 1232124 412604 6767780 7227716"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fd, err := os.Open(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer fd.Close()
+
+			header, rows, err := Parse(fd, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(header, tt.header) {
+				t.Errorf("header = %q, want %q", header, tt.header)
+			}
+			if len(rows) != tt.rows {
+				t.Fatalf("got %d rows, want %d", len(rows), tt.rows)
+			}
+			if !reflect.DeepEqual(rows[0], tt.first) {
+				t.Errorf("rows[0] = %q, want %q", rows[0], tt.first)
+			}
+		})
+	}
+}
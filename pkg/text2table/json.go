@@ -0,0 +1,423 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// member is a key and its value from a JSON object, kept in document order.
+type member struct {
+	key   string
+	value json.RawMessage
+}
+
+// unionTable collects rows of named values, with the header holding the
+// union of their names in the order they were first seen.
+type unionTable struct {
+	header []string
+	index  map[string]int
+	rows   []map[int]string
+}
+
+// readJSON parses a JSON array of objects, an object of objects keyed by id,
+// or a stream of newline delimited objects into a table, from the value at
+// the path in each if one is given. Nested fields are flattened into dotted
+// column names (e.g. "usage.cpu.total").
+func readJSON(data []byte, path string) ([]string, [][]string, error) {
+
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var values []json.RawMessage
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var v json.RawMessage
+		if err := dec.Decode(&v); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, err
+		}
+		if v, err = selectJSON(v, steps); err != nil {
+			return nil, nil, err
+		}
+		values = append(values, v)
+	}
+
+	t := &unionTable{index: map[string]int{}}
+	for _, v := range values {
+		if err := t.addValue(v, len(values) == 1); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return t.table()
+}
+
+// column returns the index of the named column, adding it if it's new.
+func (t *unionTable) column(name string) int {
+	i, ok := t.index[name]
+	if !ok {
+		i = len(t.header)
+		t.index[name] = i
+		t.header = append(t.header, name)
+	}
+	return i
+}
+
+// table returns the header and the rows with the values they're missing
+// left empty.
+func (t *unionTable) table() ([]string, [][]string, error) {
+	table := make([][]string, 0, len(t.rows))
+	for _, r := range t.rows {
+		row := make([]string, len(t.header))
+		for i, v := range r {
+			row[i] = v
+		}
+		table = append(table, row)
+	}
+	return t.header, table, nil
+}
+
+// addValue adds the rows held by a top level value. Only a lone object can be
+// a collection of rows, in a stream each object is a row of its own.
+func (t *unionTable) addValue(v json.RawMessage, alone bool) error {
+
+	switch jsonKind(v) {
+	case '[':
+		rows, err := elements(v)
+		if err != nil {
+			return err
+		}
+		for _, r := range rows {
+			if err := t.addRow(r, ""); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case '{':
+		if !alone {
+			return t.addRow(v, "")
+		}
+
+		fields, err := members(v)
+		if err != nil {
+			return err
+		}
+
+		// an object of objects is keyed by id
+		keyed := len(fields) > 0
+		for _, f := range fields {
+			keyed = keyed && jsonKind(f.value) == '{'
+		}
+		if keyed {
+			for _, f := range fields {
+				if err := t.addRow(f.value, f.key); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		// an object wrapping a single list of objects holds the rows in it
+		// (e.g. the "items" from kubectl -o json)
+		var list json.RawMessage
+		for _, f := range fields {
+			if jsonKind(f.value) != '[' {
+				continue
+			}
+			rows, err := elements(f.value)
+			if err != nil || len(rows) == 0 || jsonKind(rows[0]) != '{' {
+				continue
+			}
+			if list != nil {
+				list = nil
+				break
+			}
+			list = f.value
+		}
+		if list != nil {
+			return t.addValue(list, true)
+		}
+	}
+
+	return t.addRow(v, "")
+}
+
+// addRow flattens a value into a row, with the id it was keyed by (if any)
+// as the first column.
+func (t *unionTable) addRow(v json.RawMessage, id string) error {
+	row := map[int]string{}
+	set := func(key, value string) {
+		row[t.column(key)] = value
+	}
+
+	if id != "" {
+		set("id", id)
+	}
+	if err := flattenJSON("", v, set); err != nil {
+		return err
+	}
+	t.rows = append(t.rows, row)
+	return nil
+}
+
+// flattenJSON calls set for each scalar in v, keyed by the dotted path to it.
+// Numbers are kept as they were written rather than round tripped through
+// a float.
+func flattenJSON(prefix string, v json.RawMessage, set func(key, value string)) error {
+
+	path := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	// a bare scalar needs a column name too
+	name := prefix
+	if name == "" {
+		name = "value"
+	}
+
+	switch jsonKind(v) {
+	case '{':
+		fields, err := members(v)
+		if err != nil {
+			return err
+		}
+		for _, f := range fields {
+			if err := flattenJSON(path(f.key), f.value, set); err != nil {
+				return err
+			}
+		}
+
+	case '[':
+		items, err := elements(v)
+		if err != nil {
+			return err
+		}
+		for i, item := range items {
+			if err := flattenJSON(path(strconv.Itoa(i)), item, set); err != nil {
+				return err
+			}
+		}
+
+	case '"':
+		var s string
+		if err := json.Unmarshal(v, &s); err != nil {
+			return err
+		}
+		set(name, s)
+
+	case 'n':
+		set(name, "")
+
+	default:
+		set(name, string(bytes.TrimSpace(v)))
+	}
+
+	return nil
+}
+
+// jsonStep is a step of a path into a JSON value: the field of an object
+// with a key, an item of an array by index, or every item of an array.
+type jsonStep struct {
+	key   string
+	index int
+	all   bool
+}
+
+// parseJSONPath parses a path like "data.items", "$.data.items[*].spec" or
+// "results[0]['series']" into its steps.
+func parseJSONPath(path string) ([]jsonStep, error) {
+
+	p := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	var steps []jsonStep
+	for p != "" {
+		switch p[0] {
+		case '.':
+			p = p[1:]
+
+		case '[':
+			end := strings.Index(p, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("JSON path %q has an unclosed [", path)
+			}
+			inner := strings.TrimSpace(p[1:end])
+			p = p[end+1:]
+			switch {
+			case inner == "*" || inner == "":
+				steps = append(steps, jsonStep{all: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, jsonStep{key: inner[1 : len(inner)-1]})
+			default:
+				i, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("JSON path %q has an invalid index [%s]", path, inner)
+				}
+				steps = append(steps, jsonStep{index: i})
+			}
+
+		default:
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			if key := p[:end]; key == "*" {
+				steps = append(steps, jsonStep{all: true})
+			} else {
+				steps = append(steps, jsonStep{key: key})
+			}
+			p = p[end:]
+		}
+	}
+	return steps, nil
+}
+
+// selectJSON returns the value at the path in v. A path stepping into every
+// item of an array gives an array of the values it matches.
+func selectJSON(v json.RawMessage, steps []jsonStep) (json.RawMessage, error) {
+	matches, err := matchJSON(v, steps, "$")
+	if err != nil {
+		return nil, err
+	}
+	for _, step := range steps {
+		if step.all {
+			return json.Marshal(matches)
+		}
+	}
+	return matches[0], nil
+}
+
+// matchJSON returns the values at the steps of a path in v, where at is the
+// path so far for errors.
+func matchJSON(v json.RawMessage, steps []jsonStep, at string) ([]json.RawMessage, error) {
+
+	for i, step := range steps {
+		switch {
+		case step.all:
+			if jsonKind(v) != '[' {
+				return nil, fmt.Errorf("%s in the JSON isn't an array", at)
+			}
+			items, err := elements(v)
+			if err != nil {
+				return nil, err
+			}
+			matches := make([]json.RawMessage, 0, len(items))
+			for j, item := range items {
+				m, err := matchJSON(item, steps[i+1:], fmt.Sprintf("%s[%d]", at, j))
+				if err != nil {
+					return nil, err
+				}
+				matches = append(matches, m...)
+			}
+			return matches, nil
+
+		case step.key != "":
+			if jsonKind(v) != '{' {
+				return nil, fmt.Errorf("%s in the JSON isn't an object", at)
+			}
+			fields, err := members(v)
+			if err != nil {
+				return nil, err
+			}
+			at += "." + step.key
+			v = nil
+			for _, f := range fields {
+				if f.key == step.key {
+					v = f.value
+				}
+			}
+			if v == nil {
+				return nil, fmt.Errorf("%s isn't in the JSON", at)
+			}
+
+		default:
+			if jsonKind(v) != '[' {
+				return nil, fmt.Errorf("%s in the JSON isn't an array", at)
+			}
+			items, err := elements(v)
+			if err != nil {
+				return nil, err
+			}
+			at += fmt.Sprintf("[%d]", step.index)
+			if step.index < 0 || step.index >= len(items) {
+				return nil, fmt.Errorf("%s isn't in the JSON", at)
+			}
+			v = items[step.index]
+		}
+	}
+	return []json.RawMessage{v}, nil
+}
+
+// members returns the fields of a JSON object in the order they were written.
+func members(v json.RawMessage) ([]member, error) {
+	dec := json.NewDecoder(bytes.NewReader(v))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	var fields []member
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := t.(string)
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, member{key: key, value: value})
+	}
+
+	return fields, nil
+}
+
+// elements returns the items of a JSON array.
+func elements(v json.RawMessage) ([]json.RawMessage, error) {
+	var items []json.RawMessage
+	err := json.Unmarshal(v, &items)
+	return items, err
+}
+
+// jsonKind returns the first character of a JSON value, which tells an
+// object, array, string or null apart from a number or boolean.
+func jsonKind(v []byte) byte {
+	v = bytes.TrimSpace(v)
+	if len(v) == 0 {
+		return 0
+	}
+	return v[0]
+}
+
+// looksLikeJSON reports whether the input starts with a JSON object or array.
+func looksLikeJSON(data []byte) bool {
+	if k := jsonKind(data); k != '{' && k != '[' {
+		return false
+	}
+	var v json.RawMessage
+	return json.NewDecoder(bytes.NewReader(data)).Decode(&v) == nil
+}
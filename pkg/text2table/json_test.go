@@ -0,0 +1,170 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		header []string
+		table  [][]string
+	}{
+		{
+			name:   "newline delimited",
+			file:   "testdata/docker-stats.ndjson",
+			header: []string{"BlockIO", "CPUPerc", "Container", "ID", "MemPerc", "MemUsage", "Name", "NetIO", "PIDs"},
+			table: [][]string{
+				{"1.2MB / 0B", "0.15%", "4c01db0b339c", "4c01db0b339c", "0.42%", "12.3MiB / 2GiB", "web", "1.05kB / 0B", "3"},
+				{"54.1MB / 1.9GB", "2.31%", "d7886598dbe2", "d7886598dbe2", "3.71%", "76MiB / 2GiB", "db", "8.4MB / 12.6MB", "11"},
+			},
+		},
+		{
+			name:   "keyed by id",
+			file:   "testdata/services.json",
+			header: []string{"id", "port", "usage.cpu.total", "usage.cpu.user", "healthy", "tags.0", "tags.1", "owner"},
+			table: [][]string{
+				{"api", "8080", "1234567890123", "0.25", "true", "", "", ""},
+				{"worker", "9090", "42", "", "", "batch", "nightly", ""},
+			},
+		},
+		{
+			name:   "list wrapped in an object",
+			file:   "testdata/kubectl-pods.json",
+			header: []string{"kind", "metadata.name", "metadata.namespace", "status.phase", "status.podIP"},
+			table: [][]string{
+				{"Pod", "coredns-5d78c9869d-8xk2p", "kube-system", "Running", "10.244.0.2"},
+				{"Pod", "etcd-minikube", "kube-system", "Running", "192.168.49.2"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fd, err := os.Open(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer fd.Close()
+
+			header, table, err := NewTable(fd)
+			if err != nil {
+				t.Fatalf("NewTable() error = %v", err)
+			}
+			if !reflect.DeepEqual(header, tt.header) {
+				t.Errorf("NewTable() header = %q, want %q", header, tt.header)
+			}
+			if !reflect.DeepEqual(table, tt.table) {
+				t.Errorf("NewTable() table = %q, want %q", table, tt.table)
+			}
+		})
+	}
+}
+
+func TestParseJSONArray(t *testing.T) {
+	input := `[{"a": 1, "b": {"c": "x"}}, {"b": {"c": "y"}, "d": 2.50}]`
+	header, table, err := Parse(strings.NewReader(input), Options{Format: FormatJSON})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if want := []string{"a", "b.c", "d"}; !reflect.DeepEqual(header, want) {
+		t.Errorf("Parse() header = %q, want %q", header, want)
+	}
+	if want := [][]string{{"1", "x", ""}, {"", "y", "2.50"}}; !reflect.DeepEqual(table, want) {
+		t.Errorf("Parse() table = %q, want %q", table, want)
+	}
+}
+
+func TestParseJSONSelect(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		header  []string
+		table   [][]string
+		wantErr bool
+	}{
+		{
+			name:   "dotted",
+			path:   "data.backends",
+			header: []string{"name", "servers.0.name", "servers.0.addr", "servers.0.up", "servers.0.sessions", "servers.1.name", "servers.1.addr", "servers.1.up", "servers.1.sessions"},
+			table: [][]string{
+				{"web", "web1", "10.0.0.11:8080", "true", "12", "web2", "10.0.0.12:8080", "false", "0"},
+				{"api", "api1", "10.0.0.21:9000", "true", "40", "", "", "", ""},
+			},
+		},
+		{
+			name:   "every item",
+			path:   "$.data.backends[*].servers[*]",
+			header: []string{"name", "addr", "up", "sessions"},
+			table: [][]string{
+				{"web1", "10.0.0.11:8080", "true", "12"},
+				{"web2", "10.0.0.12:8080", "false", "0"},
+				{"api1", "10.0.0.21:9000", "true", "40"},
+			},
+		},
+		{
+			name:   "index and quoted key",
+			path:   "$['data'].backends[1].servers",
+			header: []string{"name", "addr", "up", "sessions"},
+			table:  [][]string{{"api1", "10.0.0.21:9000", "true", "40"}},
+		},
+		{
+			name:   "scalar",
+			path:   "data.count",
+			header: []string{"value"},
+			table:  [][]string{{"2"}},
+		},
+		{
+			name:    "missing",
+			path:    "data.frontends",
+			wantErr: true,
+		},
+		{
+			name:    "not an array",
+			path:    "data[0]",
+			wantErr: true,
+		},
+		{
+			name:    "unclosed",
+			path:    "data.backends[0",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fd, err := os.Open("testdata/admin-status.json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer fd.Close()
+
+			header, table, err := Parse(fd, Options{Select: tt.path})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(header, tt.header) {
+				t.Errorf("Parse() header = %q, want %q", header, tt.header)
+			}
+			if !reflect.DeepEqual(table, tt.table) {
+				t.Errorf("Parse() table = %q, want %q", table, tt.table)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+var logfmtEscapes = strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n", `\t`, "\t")
+
+// readLogfmt parses lines of key=value pairs, where values with spaces are
+// quoted (e.g. `level=info msg="request done" took=12ms`). The header holds
+// the keys in the order they were first seen, and keys missing from a line
+// are left empty in its row.
+func readLogfmt(data []byte, opts Options) ([]string, [][]string, error) {
+
+	t := &unionTable{index: map[string]int{}}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || (opts.Comment != 0 && strings.HasPrefix(line, string(opts.Comment))) {
+			continue
+		}
+
+		pairs, _ := parseLogfmt(line)
+		if len(pairs) == 0 {
+			continue
+		}
+		row := map[int]string{}
+		for _, p := range pairs {
+			row[t.column(p.name)] = p.value
+		}
+		t.rows = append(t.rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return t.table()
+}
+
+// parseLogfmt splits a line into its key=value pairs, also returning how
+// many were bare keys. A bare key is a flag set to "true".
+func parseLogfmt(line string) ([]label, int) {
+
+	var pairs []label
+	bare := 0
+	for i := 0; i < len(line); {
+		if line[i] == ' ' || line[i] == '\t' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' && line[i] != '\t' && line[i] != '"' {
+			i++
+		}
+		key := line[start:i]
+
+		if i >= len(line) || line[i] != '=' {
+			// skip stray quoted text
+			if i < len(line) && line[i] == '"' {
+				_, i = logfmtValue(line, i)
+			}
+			if key != "" {
+				pairs = append(pairs, label{name: key, value: "true"})
+				bare++
+			}
+			continue
+		}
+
+		var value string
+		value, i = logfmtValue(line, i+1)
+		if key != "" {
+			pairs = append(pairs, label{name: key, value: value})
+		}
+	}
+
+	return pairs, bare
+}
+
+// logfmtValue reads a bare or quoted value starting at i, returning it and
+// the offset after it.
+func logfmtValue(line string, i int) (string, int) {
+
+	if i >= len(line) || line[i] != '"' {
+		start := i
+		for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+			i++
+		}
+		return line[start:i], i
+	}
+
+	for j := i + 1; j < len(line); j++ {
+		if line[j] == '\\' {
+			j++
+		} else if line[j] == '"' {
+			return logfmtEscapes.Replace(line[i+1 : j]), j + 1
+		}
+	}
+
+	// an unterminated quote runs to the end of the line
+	return logfmtEscapes.Replace(line[i+1:]), len(line)
+}
+
+// looksLikeLogfmt reports whether the first line of the input is made up of
+// nothing but key=value pairs.
+func looksLikeLogfmt(data []byte) bool {
+	first := string(bytes.TrimSpace(data))
+	if i := strings.IndexByte(first, '\n'); i >= 0 {
+		first = first[:i]
+	}
+
+	pairs, bare := parseLogfmt(first)
+	return len(pairs) >= 2 && bare == 0
+}
@@ -0,0 +1,98 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseLogfmt(t *testing.T) {
+	fd, err := os.Open("testdata/service.logfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	header, table, err := NewTable(fd)
+	if err != nil {
+		t.Fatalf("NewTable() error = %v", err)
+	}
+
+	wantHeader := []string{"ts", "level", "path", "status", "took", "msg", "client", "retry"}
+	if !reflect.DeepEqual(header, wantHeader) {
+		t.Errorf("NewTable() header = %q, want %q", header, wantHeader)
+	}
+	want := [][]string{
+		{"2026-10-18T09:12:01Z", "info", "/api/users", "200", "12ms", "request done", "", ""},
+		{"2026-10-18T09:12:02Z", "warn", "/api/orders", "429", "3ms", "rate limited", "10.0.0.7", ""},
+		{"2026-10-18T09:12:02Z", "info", "/healthz", "200", "0.4ms", "", "", ""},
+		{"2026-10-18T09:12:03Z", "error", "/api/orders", "500", "1.2s", `upstream said "no"`, "", "true"},
+	}
+	if !reflect.DeepEqual(table, want) {
+		t.Errorf("NewTable() table = %q, want %q", table, want)
+	}
+}
+
+func Test_parseLogfmt(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		pairs []label
+		bare  int
+	}{
+		{
+			name:  "bare values",
+			line:  "a=1 b=two",
+			pairs: []label{{"a", "1"}, {"b", "two"}},
+		},
+		{
+			name:  "quoted with spaces and escapes",
+			line:  `msg="hello \"world\"\tagain" path="/a b"`,
+			pairs: []label{{"msg", "hello \"world\"\tagain"}, {"path", "/a b"}},
+		},
+		{
+			name:  "empty values",
+			line:  `a= b="" c=3`,
+			pairs: []label{{"a", ""}, {"b", ""}, {"c", "3"}},
+		},
+		{
+			name:  "flag",
+			line:  "debug a=1",
+			pairs: []label{{"debug", "true"}, {"a", "1"}},
+			bare:  1,
+		},
+		{
+			name:  "unterminated quote",
+			line:  `a=1 msg="cut off`,
+			pairs: []label{{"a", "1"}, {"msg", "cut off"}},
+		},
+		{
+			name:  "not logfmt",
+			line:  "USER   PID  %CPU",
+			pairs: []label{{"USER", "true"}, {"PID", "true"}, {"%CPU", "true"}},
+			bare:  3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs, bare := parseLogfmt(tt.line)
+			if !reflect.DeepEqual(pairs, tt.pairs) || bare != tt.bare {
+				t.Errorf("parseLogfmt() = %q, %d, want %q, %d", pairs, bare, tt.pairs, tt.bare)
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// label is a name and value pair of a Prometheus series.
+type label struct {
+	name  string
+	value string
+}
+
+// promSeries is a row of a Prometheus table, holding the sample value of a
+// counter or gauge, or the buckets or quantiles of a histogram or summary.
+type promSeries struct {
+	id     string
+	labels []label
+	value  string
+	extra  map[string]string
+}
+
+// promTable collects series with the label names and the bucket and quantile
+// columns in the order they were first seen.
+type promTable struct {
+	types  map[string]string
+	family string
+	series []*promSeries
+	index  map[string]*promSeries
+	labels []string
+	extra  []string
+	seen   map[string]bool
+}
+
+var labelEscapes = strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n")
+var labelQuotes = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// readPrometheus parses the Prometheus and OpenMetrics text exposition
+// format, with a row for each series and a column for each label. Histograms
+// and summaries are a row for each set of labels with columns for their
+// buckets (e.g. "le=0.5") or quantiles (e.g. "quantile=0.99"), sum and count.
+// Only the metric families matching the family pattern are read if it's set.
+func readPrometheus(data []byte, family string) ([]string, [][]string, error) {
+
+	if family != "" {
+		if _, err := path.Match(family, ""); err != nil {
+			return nil, nil, fmt.Errorf("bad metric family pattern %q: %s", family, err)
+		}
+	}
+
+	t := &promTable{
+		types:  map[string]string{},
+		family: family,
+		index:  map[string]*promSeries{},
+		seen:   map[string]bool{},
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			fields := strings.Fields(line)
+			if len(fields) >= 4 && fields[1] == "TYPE" {
+				t.types[fields[2]] = fields[3]
+			}
+			continue
+		}
+
+		if err := t.addSample(line); err != nil {
+			return nil, nil, fmt.Errorf("line %d: %s", n, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if family != "" && len(t.series) == 0 {
+		return nil, nil, fmt.Errorf("no metric family matches %q", family)
+	}
+
+	header := append([]string{"series"}, t.labels...)
+	header = append(header, "value")
+	header = append(header, t.extra...)
+
+	table := make([][]string, 0, len(t.series))
+	for _, s := range t.series {
+		row := make([]string, len(header))
+		row[0] = s.id
+		for _, l := range s.labels {
+			for i, name := range t.labels {
+				if name == l.name {
+					row[i+1] = l.value
+				}
+			}
+		}
+		row[len(t.labels)+1] = s.value
+		for i, name := range t.extra {
+			row[len(t.labels)+2+i] = s.extra[name]
+		}
+		table = append(table, row)
+	}
+
+	return header, table, nil
+}
+
+// addSample adds a sample line like `http_requests_total{code="200"} 1027`
+// to its series.
+func (t *promTable) addSample(line string) error {
+
+	// drop OpenMetrics exemplars
+	if i := strings.Index(line, " # "); i >= 0 {
+		line = line[:i]
+	}
+
+	name, labels, rest, err := parseSample(line)
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return fmt.Errorf("bad sample value %q", rest)
+	}
+	value := fields[0]
+
+	family, typ := t.familyOf(name)
+	if t.family != "" {
+		if ok, _ := path.Match(t.family, family); !ok {
+			return nil
+		}
+	}
+
+	// the bucket or quantile label of a histogram or summary is a column
+	column := ""
+	if typ == "histogram" || typ == "summary" || typ == "gaugehistogram" {
+		switch strings.TrimPrefix(name, family) {
+		case "_sum", "_gsum":
+			column = "sum"
+		case "_count", "_gcount":
+			column = "count"
+		case "_created":
+			column = "created"
+		default:
+			kept := labels[:0]
+			for _, l := range labels {
+				if l.name == "le" || l.name == "quantile" {
+					column = l.name + "=" + l.value
+				} else {
+					kept = append(kept, l)
+				}
+			}
+			labels = kept
+		}
+	}
+
+	id := family
+	if len(labels) > 0 {
+		pairs := make([]string, len(labels))
+		for i, l := range labels {
+			pairs[i] = l.name + `="` + labelQuotes.Replace(l.value) + `"`
+		}
+		id += "{" + strings.Join(pairs, ",") + "}"
+	}
+
+	s, ok := t.index[id]
+	if !ok {
+		s = &promSeries{id: id, labels: labels, extra: map[string]string{}}
+		t.index[id] = s
+		t.series = append(t.series, s)
+		for _, l := range labels {
+			t.addColumn(&t.labels, "label "+l.name, l.name)
+		}
+	}
+
+	if column == "" {
+		s.value = value
+	} else {
+		s.extra[column] = value
+		t.addColumn(&t.extra, "extra "+column, column)
+	}
+
+	return nil
+}
+
+// addColumn adds a column name the first time it's seen.
+func (t *promTable) addColumn(columns *[]string, key, name string) {
+	if !t.seen[key] {
+		t.seen[key] = true
+		*columns = append(*columns, name)
+	}
+}
+
+// familyOf returns the metric family a sample belongs to and its type, where
+// the samples of histograms, summaries and OpenMetrics counters have a
+// suffix added to the name of the family.
+func (t *promTable) familyOf(name string) (string, string) {
+	if typ, ok := t.types[name]; ok {
+		return name, typ
+	}
+	for _, suffix := range []string{"_bucket", "_sum", "_count", "_gsum", "_gcount", "_total", "_created", "_info"} {
+		if base := strings.TrimSuffix(name, suffix); base != name {
+			if typ, ok := t.types[base]; ok {
+				return base, typ
+			}
+		}
+	}
+	return name, "untyped"
+}
+
+// parseSample splits a sample line into the metric name, its labels, and the
+// rest of the line holding the value and timestamp.
+func parseSample(line string) (string, []label, string, error) {
+
+	i := strings.IndexAny(line, "{ \t")
+	if i <= 0 {
+		return "", nil, "", fmt.Errorf("bad sample %q", line)
+	}
+	name, rest := line[:i], line[i:]
+	if rest[0] != '{' {
+		return name, nil, rest, nil
+	}
+
+	var labels []label
+	rest = rest[1:]
+	for {
+		rest = strings.TrimLeft(rest, " \t,")
+		if strings.HasPrefix(rest, "}") {
+			return name, labels, rest[1:], nil
+		}
+
+		eq := strings.Index(rest, "=")
+		if eq <= 0 {
+			return "", nil, "", fmt.Errorf("bad labels in %q", line)
+		}
+		l := label{name: strings.TrimSpace(rest[:eq])}
+		rest = strings.TrimLeft(rest[eq+1:], " \t")
+		if !strings.HasPrefix(rest, `"`) {
+			return "", nil, "", fmt.Errorf("unquoted label value in %q", line)
+		}
+
+		// find the closing quote, skipping escaped characters
+		end := -1
+		for j := 1; j < len(rest); j++ {
+			if rest[j] == '\\' {
+				j++
+			} else if rest[j] == '"' {
+				end = j
+				break
+			}
+		}
+		if end < 0 {
+			return "", nil, "", fmt.Errorf("unterminated label value in %q", line)
+		}
+		l.value = labelEscapes.Replace(rest[1:end])
+		labels = append(labels, l)
+		rest = rest[end+1:]
+	}
+}
+
+// looksLikePrometheus reports whether the input starts with the HELP or TYPE
+// comments of the Prometheus text format.
+func looksLikePrometheus(data []byte) bool {
+	first := bytes.TrimSpace(data)
+	if i := bytes.IndexByte(first, '\n'); i >= 0 {
+		first = first[:i]
+	}
+	return bytes.HasPrefix(first, []byte("# HELP ")) || bytes.HasPrefix(first, []byte("# TYPE "))
+}
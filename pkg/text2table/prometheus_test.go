@@ -0,0 +1,134 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePrometheus(t *testing.T) {
+	tests := []struct {
+		name   string
+		family string
+		header []string
+		table  [][]string
+	}{
+		{
+			name:   "counter",
+			family: "node_cpu_*",
+			header: []string{"series", "cpu", "mode", "value"},
+			table: [][]string{
+				{`node_cpu_seconds_total{cpu="0",mode="idle"}`, "0", "idle", "451303.6"},
+				{`node_cpu_seconds_total{cpu="0",mode="system"}`, "0", "system", "1803.25"},
+				{`node_cpu_seconds_total{cpu="0",mode="user"}`, "0", "user", "6212.01"},
+				{`node_cpu_seconds_total{cpu="1",mode="idle"}`, "1", "idle", "452011.07"},
+				{`node_cpu_seconds_total{cpu="1",mode="system"}`, "1", "system", "1755.4"},
+				{`node_cpu_seconds_total{cpu="1",mode="user"}`, "1", "user", "6001.93"},
+			},
+		},
+		{
+			name:   "summary",
+			family: "go_gc_duration_seconds",
+			header: []string{"series", "value", "quantile=0", "quantile=0.25", "quantile=0.5", "quantile=0.75", "quantile=1", "sum", "count"},
+			table: [][]string{
+				{"go_gc_duration_seconds", "", "2.4512e-05", "3.7106e-05", "4.3908e-05", "6.1204e-05", "0.000532811", "0.089124871", "1534"},
+			},
+		},
+		{
+			name:   "histogram",
+			family: "prometheus_http_request_duration_seconds",
+			header: []string{"series", "handler", "value", "le=0.1", "le=0.5", "le=+Inf", "sum", "count"},
+			table: [][]string{
+				{`prometheus_http_request_duration_seconds{handler="/metrics"}`, "/metrics", "", "2791", "2795", "2796", "31.53", "2796"},
+			},
+		},
+		{
+			name:   "escaped labels",
+			family: "node_uname_info",
+			header: []string{"series", "domainname", "machine", "nodename", "release", "sysname", "version", "value"},
+			table: [][]string{
+				{`node_uname_info{domainname="(none)",machine="x86_64",nodename="build \"box\"",release="5.15.0-91-generic",sysname="Linux",version="#101-Ubuntu SMP Tue Nov 14 13:30:08 UTC 2023"}`,
+					"(none)", "x86_64", `build "box"`, "5.15.0-91-generic", "Linux", "#101-Ubuntu SMP Tue Nov 14 13:30:08 UTC 2023", "1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fd, err := os.Open("testdata/node-exporter.prom")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer fd.Close()
+
+			header, table, err := Parse(fd, Options{Family: tt.family})
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(header, tt.header) {
+				t.Errorf("Parse() header = %q, want %q", header, tt.header)
+			}
+			if !reflect.DeepEqual(table, tt.table) {
+				t.Errorf("Parse() table = %q, want %q", table, tt.table)
+			}
+		})
+	}
+}
+
+func TestParsePrometheusAll(t *testing.T) {
+	fd, err := os.Open("testdata/node-exporter.prom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	table, err := ReadTable(fd, Options{})
+	if err != nil {
+		t.Fatalf("ReadTable() error = %v", err)
+	}
+	if len(table.Rows) != 13 {
+		t.Errorf("ReadTable() got %d rows, want 13", len(table.Rows))
+	}
+	if got := table.Columns[table.Key[0]].Name; got != "series" {
+		t.Errorf("ReadTable() key = %q, want series", got)
+	}
+	if c := table.Columns[table.Column("value")]; c.Type != Numeric {
+		t.Errorf("ReadTable() value column type = %v, want numeric", c.Type)
+	}
+}
+
+func TestParsePrometheusErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		family string
+	}{
+		{name: "no family", input: "# TYPE up gauge\nup 1\n", family: "down"},
+		{name: "bad pattern", input: "# TYPE up gauge\nup 1\n", family: "[up"},
+		{name: "unterminated label", input: "# TYPE up gauge\nup{job=\"x} 1\n"},
+		{name: "unquoted label", input: "# TYPE up gauge\nup{job=x} 1\n"},
+		{name: "no value", input: "# TYPE up gauge\nup{job=\"x\"}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Parse(strings.NewReader(tt.input), Options{Family: tt.family})
+			if err == nil {
+				t.Errorf("Parse() error = nil, want an error")
+			}
+		})
+	}
+}
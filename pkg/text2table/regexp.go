@@ -0,0 +1,72 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// readRegexp picks the columns out of each line with the named groups of a
+// regexp, which are the header. Lines that don't match are skipped and
+// counted.
+func readRegexp(data []byte, opts Options) ([]string, [][]string, int, error) {
+
+	re, err := regexp.Compile(opts.Regexp)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	var header []string
+	var groups []int
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			header = append(header, name)
+			groups = append(groups, i)
+		}
+	}
+	if len(header) == 0 {
+		return nil, nil, 0, fmt.Errorf("regexp %q has no named groups like (?P<name>...)", opts.Regexp)
+	}
+
+	var table [][]string
+	skipped := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || (opts.Comment != 0 && strings.HasPrefix(line, string(opts.Comment))) {
+			continue
+		}
+
+		m := re.FindStringSubmatch(line)
+		if m == nil {
+			skipped++
+			continue
+		}
+		row := make([]string, len(groups))
+		for i, g := range groups {
+			row[i] = m[g]
+		}
+		table = append(table, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, 0, err
+	}
+
+	return header, table, skipped, nil
+}
@@ -0,0 +1,74 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadRegexp(t *testing.T) {
+	fd, err := os.Open("testdata/lsof-i.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	opts := Options{Regexp: `^(?P<command>\S+)\s+(?P<pid>\d+)\s+(?P<user>\S+).*\s(?P<proto>TCP|UDP) (?P<name>\S+) \((?P<state>\w+)\)$`}
+	table, err := ReadTable(fd, opts)
+	if err != nil {
+		t.Fatalf("ReadTable() error = %v", err)
+	}
+
+	if want := []string{"command", "pid", "user", "proto", "name", "state"}; !reflect.DeepEqual(table.Header(), want) {
+		t.Errorf("ReadTable() header = %q, want %q", table.Header(), want)
+	}
+	if len(table.Rows) != 6 {
+		t.Errorf("ReadTable() got %d rows, want 6", len(table.Rows))
+	}
+	// the header and the UDP socket without a state don't match
+	if table.Skipped != 2 {
+		t.Errorf("ReadTable() skipped = %d, want 2", table.Skipped)
+	}
+
+	row := table.Rows[5]
+	want := []string{"firefox", "4129", "cove", "TCP", "10.0.0.2:51544->151.101.1.69:https", "ESTABLISHED"}
+	for i, c := range row.Cells {
+		if c.Text != want[i] {
+			t.Errorf("ReadTable() row 5 = %+v, want %q", row.Cells, want)
+			break
+		}
+	}
+}
+
+func TestReadRegexpErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		regexp string
+	}{
+		{name: "bad regexp", regexp: `(?P<a>`},
+		{name: "no named groups", regexp: `(\d+) (\w+)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Parse(strings.NewReader("1 a\n"), Options{Regexp: tt.regexp})
+			if err == nil {
+				t.Errorf("Parse() error = nil, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Whitespace is the separator of fields split on runs of whitespace or by
+// their column offsets.
+const Whitespace = ' '
+
+// sniffLines is how many lines of a table are looked at to find the
+// separator.
+const sniffLines = 20
+
+// separators are the separators SniffSeparator chooses from, in the order
+// they're preferred when they fit the input as well as each other.
+var separators = []rune{'\t', ',', ';', '|', ':'}
+
+// separatorNames are the names ParseSeparator accepts.
+var separatorNames = map[string]rune{
+	"comma": ',', "tab": '\t', "semicolon": ';', "pipe": '|', "colon": ':',
+	"space": Whitespace, "whitespace": Whitespace,
+}
+
+// ParseSeparator returns the separator with the given name (e.g. "comma" or
+// "tab"), or the separator itself if it's a single character.
+func ParseSeparator(name string) (rune, error) {
+	if name == "" {
+		return 0, nil
+	}
+	if sep, ok := separatorNames[strings.ToLower(name)]; ok {
+		return sep, nil
+	}
+	if name == `\t` {
+		return '\t', nil
+	}
+	if utf8.RuneCountInString(name) == 1 {
+		r, _ := utf8.DecodeRuneInString(name)
+		return r, nil
+	}
+	return 0, fmt.Errorf("unknown separator %q", name)
+}
+
+// SniffSeparator works out what separates the fields of a table from its
+// first lines. A separator has to split every line into as many fields as
+// the header, which needs at least two, and commas in prose like the summary
+// from top don't count. Whitespace fits an aligned table whose values line
+// up with its titles, where a separator inside a title doesn't count, and
+// otherwise is scored by the share of lines that fit after skipping any
+// preamble, allowing rows with extra fields for a last column with spaces in
+// it. The best score wins, and then the most fields,
+// so a colon in a title like "TIME:" or a comma in a value don't change how
+// an aligned table is read.
+func SniffSeparator(data []byte, comment rune) rune {
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for i := 0; i < maxPreamble+sniffLines && scanner.Scan(); i++ {
+		lines = append(lines, scanner.Text())
+	}
+
+	start, _ := FindTable(lines)
+	var counts []int
+	var body []string
+	for _, line := range lines[start:] {
+		if n := len(strings.Fields(line)); n > 0 && len(counts) < sniffLines {
+			counts = append(counts, n)
+			body = append(body, line)
+		}
+	}
+	best := rune(Whitespace)
+	score, fields := fitness(counts, true)
+	var layout *fixedLayout
+	if len(body) > 1 {
+		layout = newFixedLayout(body[0], body[1:])
+	}
+	if layout != nil {
+		score, fields = 1, len(layout.names)
+	}
+	prose := looksLikeProse(lines[start:])
+
+	for _, sep := range separators {
+		if (sep == ',' && prose) || (layout != nil && cutsTitle(layout, sep)) {
+			continue
+		}
+		s, n := fitness(countFields(data, sep, comment), false)
+		if n == 0 || s < 1 {
+			continue
+		}
+		if s > score || (s == score && (n > fields || (n == fields && best == Whitespace))) {
+			best, score, fields = sep, s, n
+		}
+	}
+
+	return best
+}
+
+// cutsTitle reports whether sep is inside a word of one of the titles of an
+// aligned table, like the colon of "MAJ:MIN" from lsblk, which it would cut
+// in two rather than separate.
+func cutsTitle(layout *fixedLayout, sep rune) bool {
+	for _, name := range layout.names {
+		for _, word := range strings.Fields(name) {
+			if strings.ContainsRune(strings.Trim(word, string(sep)), sep) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fitness returns the share of records that have as many fields as the
+// first, or at least as many if more are allowed, and the number of fields.
+// A score below 0.8 or a header with one field doesn't fit at all.
+func fitness(counts []int, more bool) (float64, int) {
+	if len(counts) == 0 || counts[0] < 2 {
+		return 0, 0
+	}
+	fit := 0
+	for _, n := range counts {
+		if n == counts[0] || (more && n > counts[0]) {
+			fit++
+		}
+	}
+	s := float64(fit) / float64(len(counts))
+	if s < 0.8 {
+		return 0, 0
+	}
+	return s, counts[0]
+}
+
+// looksLikeProse reports whether the lines with commas in them are mostly
+// summaries like "Tasks: 287 total,   1 running, 286 sleeping" from top,
+// where the commas separate phrases rather than the fields of a table.
+func looksLikeProse(lines []string) bool {
+	commas, summaries := 0, 0
+	for _, line := range lines {
+		parts := strings.Split(line, ",")[1:]
+		if len(parts) == 0 {
+			continue
+		}
+		commas++
+
+		phrases := 0
+		for _, p := range parts {
+			if strings.TrimSpace(p) != p && len(strings.Fields(p)) > 1 {
+				phrases++
+			}
+		}
+		if len(parts) > 1 && phrases*2 > len(parts) {
+			summaries++
+		}
+	}
+	return commas > 0 && summaries*2 > commas
+}
+
+// countFields returns the number of fields in each of the first records of
+// the input when split by sep, minding quotes.
+func countFields(data []byte, sep rune, comment rune) []int {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = sep
+	r.Comment = comment
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	var counts []int
+	for len(counts) < sniffLines {
+		record, err := r.Read()
+		if err != nil {
+			break
+		}
+		counts = append(counts, len(record))
+	}
+	return counts
+}
@@ -0,0 +1,217 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSniffSeparator(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		file  string
+		want  rune
+	}{
+		{
+			name:  "comma",
+			input: "name,size\na,1\nb,2\n",
+			want:  ',',
+		},
+		{
+			name:  "quoted comma",
+			input: "name,note\na,\"x, y\"\nb,z\n",
+			want:  ',',
+		},
+		{
+			name:  "tab",
+			input: "name\tsize\na\t1\n",
+			want:  '\t',
+		},
+		{
+			name:  "semicolon",
+			input: "name;size\na;1,5\nb;2,0\n",
+			want:  ';',
+		},
+		{
+			name:  "pipe",
+			input: "name|size|owner\na|1|x\nb|2|y\n",
+			want:  '|',
+		},
+		{
+			name:  "colon",
+			input: "user:uid:shell\nroot:0:/bin/sh\ncove:501:/bin/zsh\n",
+			want:  ':',
+		},
+		{
+			name:  "colon in a title",
+			input: "NAME    TIME:   ADDR\na       3:04    10.0.0.1:80\nb       4:10    10.0.0.2:80\n",
+			want:  Whitespace,
+		},
+		{
+			name:  "comma in a value",
+			input: "NAME    NOTE\na       hello, world\nb       fine\n",
+			want:  Whitespace,
+		},
+		{
+			name:  "preamble with commas",
+			input: "Tasks: 287 total, 1 running\n\nPID   USER\n1     root\n2     cove\n",
+			want:  Whitespace,
+		},
+		{
+			name:  "uneven commas",
+			input: "a,b,c\nd,e\nf,g,h,i\n",
+			want:  Whitespace,
+		},
+		{
+			name:  "summary",
+			input: "Tasks: 287 total,   1 running, 286 sleeping\nMiB Mem :  15936.2 total,   1203.4 free,   8123.6 used\n",
+			want:  Whitespace,
+		},
+		{
+			name: "ss -tan",
+			file: "testdata/ss-tan.txt",
+			want: Whitespace,
+		},
+		{
+			name: "lsblk",
+			file: "testdata/lsblk.txt",
+			want: Whitespace,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(tt.input)
+			if tt.file != "" {
+				var err error
+				if data, err = ioutil.ReadFile(tt.file); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := SniffSeparator(data, 0); got != tt.want {
+				t.Errorf("SniffSeparator() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSeparator(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    rune
+		wantErr bool
+	}{
+		{name: "", want: 0},
+		{name: "comma", want: ','},
+		{name: "Tab", want: '\t'},
+		{name: `\t`, want: '\t'},
+		{name: "space", want: Whitespace},
+		{name: "!", want: '!'},
+		{name: "::", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSeparator(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSeparator() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSeparator() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSniffed(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		opts   Options
+		sep    rune
+		header []string
+		first  []string
+	}{
+		{
+			name:   "colon in the header",
+			file:   "testdata/haproxy-servers.txt",
+			sep:    Whitespace,
+			header: []string{"BACKEND", "SERVER", "ADDR", "STATUS", "TIME:", "WEIGHT"},
+			first:  []string{"web", "web1", "10.0.0.11:8080", "UP", "3d2h", "100"},
+		},
+		{
+			name:   "semicolons",
+			file:   "testdata/umsatz.csv",
+			sep:    ';',
+			header: []string{"datum", "filiale", "umsatz", "notiz"},
+			first:  []string{"2026-10-01", "Berlin", "1.204,50", "Rabatt; 5%"},
+		},
+		{
+			name:   "separator given",
+			file:   "testdata/haproxy-servers.txt",
+			opts:   Options{Separator: ':'},
+			sep:    ':',
+			header: []string{"BACKEND    SERVER       ADDR              STATUS   TIME", "WEIGHT"},
+			first:  []string{"web        web1         10.0.0.11", "8080    UP       3d2h      100"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fd, err := os.Open(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer fd.Close()
+
+			table, err := ReadTable(fd, tt.opts)
+			if err != nil {
+				t.Fatalf("ReadTable() error = %v", err)
+			}
+			if table.Format != FormatText || table.Separator != tt.sep {
+				t.Errorf("ReadTable() read as %s with %q, want text with %q", table.Format, table.Separator, tt.sep)
+			}
+			var header []string
+			for _, c := range table.Columns {
+				header = append(header, c.Name)
+			}
+			if !reflect.DeepEqual(header, tt.header) {
+				t.Errorf("ReadTable() header = %q, want %q", header, tt.header)
+			}
+			if len(table.Rows) == 0 {
+				t.Fatalf("ReadTable() no rows, want first %q", tt.first)
+			}
+			var first []string
+			for _, c := range table.Rows[0].Cells {
+				first = append(first, c.Text)
+			}
+			if !reflect.DeepEqual(first, tt.first) {
+				t.Errorf("ReadTable() first row = %q, want %q", first, tt.first)
+			}
+		})
+	}
+}
+
+func TestParseSeparatorOverridesFormat(t *testing.T) {
+	header, rows, err := Parse(strings.NewReader("a;b\n1;2\n"), Options{Format: FormatCSV, Separator: ';'})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if !reflect.DeepEqual(header, []string{"a", "b"}) || !reflect.DeepEqual(rows, [][]string{{"1", "2"}}) {
+		t.Errorf("Parse() = %q, %q", header, rows)
+	}
+}
@@ -0,0 +1,412 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ColumnType is the kind of data held in a column.
+type ColumnType int
+
+const (
+	// Text is free form text such as a command line
+	Text ColumnType = iota
+	// Numeric values can be used to scale cubes
+	Numeric
+	// Categorical values repeat across rows, like a user or a state
+	Categorical
+	// Time values are timestamps
+	Time
+	// Identifier values are unique to a row, like a PID or container id
+	Identifier
+)
+
+func (t ColumnType) String() string {
+	switch t {
+	case Numeric:
+		return "numeric"
+	case Categorical:
+		return "categorical"
+	case Time:
+		return "time"
+	case Identifier:
+		return "identifier"
+	}
+	return "text"
+}
+
+// Column describes a column of a Table.
+type Column struct {
+	Name string
+	Type ColumnType
+	// Unit of a numeric column
+	Unit Unit
+}
+
+// Cell is a value in a Table.
+type Cell struct {
+	// Text as it was read
+	Text string
+	// Value parsed from the text, only set if Numeric is
+	Value Value
+	// Numeric is set if the text is a number
+	Numeric bool
+	// Null is set for empty and placeholder values like "-" or "N/A"
+	Null bool
+}
+
+// Row is a row of a Table.
+type Row struct {
+	// ID identifies the row across updates of the table
+	ID    string
+	Cells []Cell
+}
+
+// Table is a table with typed columns.
+type Table struct {
+	Columns []Column
+	Rows    []Row
+	// Key holds the indexes of the columns the row ids are made from, when
+	// empty the ids are a hash of the row
+	Key []int
+	// Duplicates lists the ids that more than one row had
+	Duplicates []string
+	// Skipped is the number of lines that didn't match the regexp the
+	// table was read with
+	Skipped int
+	// Format and Separator are what the table was read as, whether they
+	// were sniffed from the input or given in the options
+	Format    Format
+	Separator rune
+}
+
+// nulls are the placeholders commands print for missing values.
+var nulls = map[string]bool{
+	"": true, "-": true, "--": true, "n/a": true, "na": true,
+	"null": true, "nil": true, "none": true, "<none>": true, "<nil>": true,
+}
+
+// timeLayouts are the timestamp formats recognized in Time columns. Times of
+// day without a date or AM/PM (e.g. "10:32") are left as durations.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC1123Z,
+	time.RFC1123,
+	time.UnixDate,
+	time.ANSIC,
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"Jan _2 15:04:05",
+	"Jan _2 15:04",
+	"Jan _2",
+	"Jan02",
+	"3:04PM",
+	"3:04pm",
+}
+
+// idName matches column names that are identifiers, like PID or CONTAINER ID.
+var idName = regexp.MustCompile(`(?i)(^|[^a-z])(id|pid|uuid|key)$`)
+
+// ReadTable parses a table in the given format from fd and infers the types
+// of its columns. The rows are identified by the key columns in the options,
+// or by the ones inferred from the table if there are none.
+func ReadTable(fd io.Reader, opts Options) (*Table, error) {
+	header, rows, how, err := parse(fd, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	t := FromRows(header, rows)
+	t.Skipped = how.skipped
+	t.Format = how.format
+	t.Separator = how.separator
+	if len(opts.Key) > 0 {
+		key, err := t.ResolveKey(opts.Key)
+		if err != nil {
+			return nil, err
+		}
+		t.SetKey(key)
+	}
+	return t, nil
+}
+
+// FromRows builds a Table from a header and rows of text, inferring the type
+// and unit of each column and which columns identify a row.
+func FromRows(header []string, rows [][]string) *Table {
+
+	t := &Table{
+		Columns: make([]Column, len(header)),
+		Rows:    make([]Row, 0, len(rows)),
+	}
+
+	for _, fields := range rows {
+		row := Row{Cells: make([]Cell, len(header))}
+		for i := range row.Cells {
+			if i < len(fields) {
+				row.Cells[i] = NewCell(fields[i])
+			} else {
+				row.Cells[i] = NewCell("")
+			}
+		}
+		t.Rows = append(t.Rows, row)
+	}
+
+	for i, name := range header {
+		t.Columns[i] = t.inferColumn(i, name)
+	}
+
+	t.SetKey(t.inferKey())
+	return t
+}
+
+// NewCell parses the text of a cell.
+func NewCell(text string) Cell {
+	c := Cell{Text: text}
+	if nulls[strings.ToLower(strings.TrimSpace(text))] {
+		c.Null = true
+		return c
+	}
+	if v, err := ParseValue(text); err == nil {
+		c.Value = v
+		c.Numeric = true
+	}
+	return c
+}
+
+// Header returns the names of the columns.
+func (t *Table) Header() []string {
+	header := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		header[i] = c.Name
+	}
+	return header
+}
+
+// Column returns the index of the named column, or -1 if there isn't one.
+func (t *Table) Column(name string) int {
+	for i, c := range t.Columns {
+		if c.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// SetKey sets the columns that identify a row and updates the row ids. Rows
+// with an id already taken have a count appended (e.g. "tcp/*:80#2") so they
+// don't replace each other, and the id is added to Duplicates.
+func (t *Table) SetKey(key []int) {
+	t.Key = key
+	t.Duplicates = nil
+
+	seen := map[string]int{}
+	for i := range t.Rows {
+		row := &t.Rows[i]
+		if len(key) == 0 {
+			row.ID = t.hashRow(row)
+		} else {
+			parts := make([]string, 0, len(key))
+			for _, k := range key {
+				if k < len(row.Cells) {
+					parts = append(parts, row.Cells[k].Text)
+				}
+			}
+			row.ID = strings.Join(parts, "/")
+		}
+
+		seen[row.ID]++
+		if n := seen[row.ID]; n > 1 {
+			if n == 2 {
+				t.Duplicates = append(t.Duplicates, row.ID)
+			}
+			row.ID += "#" + strconv.Itoa(n)
+		}
+	}
+}
+
+// ResolveKey returns the indexes of the key columns, given by name or by
+// position starting at 1 as with cut and sort.
+func (t *Table) ResolveKey(names []string) ([]int, error) {
+	var key []int
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		i := t.Column(name)
+		if i < 0 {
+			for j, c := range t.Columns {
+				if strings.EqualFold(c.Name, name) {
+					i = j
+					break
+				}
+			}
+		}
+		if i < 0 {
+			if n, err := strconv.Atoi(name); err == nil && n >= 1 && n <= len(t.Columns) {
+				i = n - 1
+			}
+		}
+		if i < 0 {
+			return nil, fmt.Errorf("unknown key column %q", name)
+		}
+		key = append(key, i)
+	}
+	return key, nil
+}
+
+// hashRow makes an id for a row in a table without key columns from the
+// values that aren't numeric, as those change from one update to the next.
+func (t *Table) hashRow(row *Row) string {
+	h := fnv.New64a()
+	all := true
+	for _, c := range t.Columns {
+		all = all && c.Type == Numeric
+	}
+	for i, c := range row.Cells {
+		if all || t.Columns[i].Type != Numeric {
+			h.Write([]byte(c.Text))
+			h.Write([]byte{0})
+		}
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// inferColumn works out the type of a column from its name and values.
+func (t *Table) inferColumn(i int, name string) Column {
+
+	col := Column{Name: name, Type: Text}
+	t.readMilli(i)
+
+	var values []Cell
+	for _, row := range t.Rows {
+		if !row.Cells[i].Null {
+			values = append(values, row.Cells[i])
+		}
+	}
+	if len(values) == 0 {
+		return col
+	}
+
+	distinct := map[string]bool{}
+	numeric, times, spaces := 0, 0, 0
+	units := map[Unit]int{}
+	for _, c := range values {
+		distinct[c.Text] = true
+		if c.Numeric {
+			numeric++
+			units[c.Value.Unit]++
+		} else if isTime(c.Text) {
+			times++
+		}
+		if strings.ContainsAny(strings.TrimSpace(c.Text), " \t") {
+			spaces++
+		}
+	}
+	unique := len(distinct) == len(values) && len(values) > 1
+
+	switch {
+	case idName.MatchString(name) && (unique || len(values) == 1):
+		col.Type = Identifier
+	case numeric == len(values):
+		col.Type = Numeric
+		most := 0
+		for _, c := range values {
+			if n := units[c.Value.Unit]; n > most {
+				col.Unit, most = c.Value.Unit, n
+			}
+		}
+	case times == len(values):
+		col.Type = Time
+	case unique && spaces*2 <= len(values):
+		col.Type = Identifier
+	case len(distinct) < len(values) && (len(distinct) <= 2 || len(distinct)*2 <= len(values)):
+		col.Type = Categorical
+	}
+
+	return col
+}
+
+// readMilli reads the values like "100m" in a column as minutes when there
+// are durations like "3d2h" among the others, as in the age column of
+// kubectl get, or else as thousandths like the millicores of kubectl top.
+func (t *Table) readMilli(i int) {
+	durations, milli := 0, 0
+	for _, row := range t.Rows {
+		c := row.Cells[i]
+		if c.Numeric && c.Value.Unit == UnitSeconds {
+			durations++
+		} else if _, ok := parseMilli(c.Text); ok && !c.Numeric {
+			milli++
+		}
+	}
+	if milli == 0 {
+		return
+	}
+
+	for j := range t.Rows {
+		c := &t.Rows[j].Cells[i]
+		n, ok := parseMilli(c.Text)
+		if !ok || c.Numeric {
+			continue
+		}
+		if durations > 0 {
+			c.Value = Value{Number: n * 60, Unit: UnitSeconds}
+		} else {
+			c.Value = Value{Number: n / 1000}
+		}
+		c.Numeric = true
+	}
+}
+
+// inferKey picks the column that identifies a row, preferring one named like
+// an id. Without one the rows are identified by a hash of their values.
+func (t *Table) inferKey() []int {
+	first := -1
+	for i, c := range t.Columns {
+		if c.Type != Identifier {
+			continue
+		}
+		if idName.MatchString(c.Name) {
+			return []int{i}
+		}
+		if first < 0 {
+			first = i
+		}
+	}
+	if first >= 0 {
+		return []int{first}
+	}
+	return nil
+}
+
+// isTime reports whether s is a timestamp in one of the known layouts.
+func isTime(s string) bool {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,240 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestReadTable(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		opts  Options
+		types []ColumnType
+		units map[string]Unit
+		key   []string
+	}{
+		{
+			name:  "ps aux",
+			file:  "testdata/ps-aux-osx.txt",
+			types: []ColumnType{Categorical, Identifier, Numeric, Numeric, Numeric, Numeric, Categorical, Categorical, Time, Numeric, Text},
+			units: map[string]Unit{"TIME": UnitSeconds, "RSS": UnitNone},
+			key:   []string{"PID"},
+		},
+		{
+			name:  "df -h",
+			file:  "testdata/df-h.txt",
+			types: []ColumnType{Text, Numeric, Numeric, Numeric, Numeric, Identifier},
+			units: map[string]Unit{"Size": UnitBytes, "Use%": UnitPercent},
+			key:   []string{"Mounted on"},
+		},
+		{
+			name:  "docker ps",
+			file:  "testdata/docker-ps.txt",
+			types: []ColumnType{Identifier, Identifier, Identifier, Numeric, Text, Identifier, Identifier},
+			units: map[string]Unit{"CREATED": UnitSeconds},
+			key:   []string{"CONTAINER ID"},
+		},
+		{
+			name:  "docker ps by name",
+			file:  "testdata/docker-ps.txt",
+			opts:  Options{Key: []string{"names"}},
+			types: []ColumnType{Identifier, Identifier, Identifier, Numeric, Text, Identifier, Identifier},
+			units: map[string]Unit{"CREATED": UnitSeconds},
+			key:   []string{"NAMES"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fd, err := os.Open(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer fd.Close()
+
+			table, err := ReadTable(fd, tt.opts)
+			if err != nil {
+				t.Fatalf("ReadTable() error = %v", err)
+			}
+
+			var types []ColumnType
+			for _, c := range table.Columns {
+				types = append(types, c.Type)
+			}
+			if !reflect.DeepEqual(types, tt.types) {
+				t.Errorf("ReadTable() types = %v, want %v", types, tt.types)
+			}
+			for name, unit := range tt.units {
+				if got := table.Columns[table.Column(name)].Unit; got != unit {
+					t.Errorf("ReadTable() column %v unit = %q, want %q", name, got, unit)
+				}
+			}
+
+			var key []string
+			for _, k := range table.Key {
+				key = append(key, table.Columns[k].Name)
+			}
+			if !reflect.DeepEqual(key, tt.key) {
+				t.Errorf("ReadTable() key = %q, want %q", key, tt.key)
+			}
+		})
+	}
+}
+
+func TestFromRows(t *testing.T) {
+	table := FromRows(
+		[]string{"name", "state", "mem", "started"},
+		[][]string{
+			{"a", "up", "1.5G", "2018-11-20 10:56:00"},
+			{"b", "up", "-", "2018-11-20 11:02:13"},
+			{"c", "down", "N/A"},
+		})
+
+	want := []Column{
+		{Name: "name", Type: Identifier},
+		{Name: "state", Type: Categorical},
+		{Name: "mem", Type: Numeric, Unit: UnitBytes},
+		{Name: "started", Type: Time},
+	}
+	if !reflect.DeepEqual(table.Columns, want) {
+		t.Errorf("FromRows() columns = %+v, want %+v", table.Columns, want)
+	}
+
+	if c := table.Rows[1].Cells[2]; !c.Null || c.Numeric {
+		t.Errorf("FromRows() cell %+v, want null", c)
+	}
+	if c := table.Rows[2].Cells[3]; !c.Null {
+		t.Errorf("FromRows() missing cell %+v, want null", c)
+	}
+	if id := table.Rows[2].ID; id != "c" {
+		t.Errorf("FromRows() row id = %q, want %q", id, "c")
+	}
+
+	table.SetKey([]int{0, 1})
+	if id := table.Rows[0].ID; id != "a/up" {
+		t.Errorf("SetKey() row id = %q, want %q", id, "a/up")
+	}
+}
+
+func TestFromRowsMilli(t *testing.T) {
+	table := FromRows(
+		[]string{"NAME", "CPU(cores)", "AGE"},
+		[][]string{
+			{"web", "250m", "5m"},
+			{"db", "1", "3d2h"},
+		})
+
+	tests := []struct {
+		row, col int
+		want     Value
+	}{
+		{row: 0, col: 1, want: Value{Number: 0.25}},
+		{row: 1, col: 1, want: Value{Number: 1}},
+		{row: 0, col: 2, want: Value{Number: 300, Unit: UnitSeconds}},
+		{row: 1, col: 2, want: Value{Number: 3*86400 + 2*3600, Unit: UnitSeconds}},
+	}
+	for _, tt := range tests {
+		if c := table.Rows[tt.row].Cells[tt.col]; !c.Numeric || c.Value != tt.want {
+			t.Errorf("FromRows() cell %q = %+v, want %+v", c.Text, c.Value, tt.want)
+		}
+	}
+	if c := table.Columns[2]; c.Type != Numeric || c.Unit != UnitSeconds {
+		t.Errorf("FromRows() column %+v, want durations", c)
+	}
+}
+
+func TestResolveKey(t *testing.T) {
+	table := FromRows([]string{"Proto", "Local Address", "Foreign Address", "State"}, nil)
+
+	tests := []struct {
+		name    string
+		key     []string
+		want    []int
+		wantErr bool
+	}{
+		{name: "name", key: []string{"State"}, want: []int{3}},
+		{name: "any case", key: []string{"state"}, want: []int{3}},
+		{name: "position", key: []string{"1"}, want: []int{0}},
+		{name: "composite", key: []string{"Proto", "Local Address", "3"}, want: []int{0, 1, 2}},
+		{name: "unknown", key: []string{"PID"}, wantErr: true},
+		{name: "out of range", key: []string{"5"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := table.ResolveKey(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetKeyDuplicates(t *testing.T) {
+	table := FromRows(
+		[]string{"Proto", "Local Address", "Foreign Address", "State"},
+		[][]string{
+			{"tcp", "10.0.0.2:22", "10.0.0.9:50122", "ESTABLISHED"},
+			{"tcp", "10.0.0.2:22", "10.0.0.7:61001", "ESTABLISHED"},
+			{"tcp", "*:80", "*:*", "LISTEN"},
+			{"tcp6", "*:80", "*:*", "LISTEN"},
+		})
+
+	table.SetKey([]int{0, 1})
+	var ids []string
+	for _, row := range table.Rows {
+		ids = append(ids, row.ID)
+	}
+	want := []string{"tcp/10.0.0.2:22", "tcp/10.0.0.2:22#2", "tcp/*:80", "tcp6/*:80"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("SetKey() ids = %q, want %q", ids, want)
+	}
+	if want := []string{"tcp/10.0.0.2:22"}; !reflect.DeepEqual(table.Duplicates, want) {
+		t.Errorf("SetKey() duplicates = %q, want %q", table.Duplicates, want)
+	}
+
+	table.SetKey([]int{0, 1, 2})
+	if table.Duplicates != nil {
+		t.Errorf("SetKey() duplicates = %q, want none", table.Duplicates)
+	}
+}
+
+func TestHashKey(t *testing.T) {
+	read := func(rows [][]string) *Table {
+		return FromRows([]string{"level", "count"}, rows)
+	}
+
+	before := read([][]string{{"info", "10"}, {"warn", "2"}, {"info", "3"}, {"warn", "1"}})
+	after := read([][]string{{"info", "12"}, {"warn", "2"}, {"info", "4"}, {"warn", "5"}})
+	if before.Key != nil {
+		t.Fatalf("FromRows() key = %v, want none", before.Key)
+	}
+	for i := range before.Rows {
+		if before.Rows[i].ID != after.Rows[i].ID {
+			t.Errorf("row %d id changed from %q to %q", i, before.Rows[i].ID, after.Rows[i].ID)
+		}
+	}
+	if before.Rows[0].ID == before.Rows[1].ID {
+		t.Errorf("rows 0 and 1 have the same id %q", before.Rows[0].ID)
+	}
+	if len(before.Duplicates) != 2 {
+		t.Errorf("FromRows() duplicates = %q, want 2", before.Duplicates)
+	}
+}
@@ -16,69 +16,293 @@ package text2table
 
 import (
 	"bufio"
+	"bytes"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"strings"
 )
 
+// Format is the layout of the text a table is parsed from.
+type Format string
+
+const (
+	// FormatAuto guesses the format from the start of the input
+	FormatAuto Format = "auto"
+	// FormatText is whitespace separated or aligned columns
+	FormatText Format = "text"
+	// FormatCSV is comma separated values as described by RFC 4180
+	FormatCSV Format = "csv"
+	// FormatTSV is tab separated values
+	FormatTSV Format = "tsv"
+	// FormatJSON is an array of objects, an object of objects keyed by id,
+	// or newline delimited objects
+	FormatJSON Format = "json"
+	// FormatPrometheus is the Prometheus and OpenMetrics text exposition
+	// format
+	FormatPrometheus Format = "prometheus"
+	// FormatLogfmt is lines of key=value pairs
+	FormatLogfmt Format = "logfmt"
+	// FormatBox is a table framed with border lines, as printed by mysql,
+	// psql and sqlite3, or a Markdown table
+	FormatBox Format = "box"
+)
+
+// Formats lists the formats accepted by ParseFormat.
+var Formats = []Format{FormatAuto, FormatText, FormatCSV, FormatTSV, FormatJSON, FormatPrometheus, FormatLogfmt, FormatBox}
+
+// ParseFormat returns the format with the given name.
+func ParseFormat(name string) (Format, error) {
+	if name == "" {
+		return FormatAuto, nil
+	}
+	for _, f := range Formats {
+		if string(f) == strings.ToLower(name) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format %q", name)
+}
+
+// Options controls how Parse reads a table.
+type Options struct {
+	// Format of the input, defaults to FormatAuto
+	Format Format
+	// Comment character that starts a line to skip in CSV, TSV and logfmt
+	// input
+	Comment rune
+	// Key names the columns that identify a row, by name or by position
+	// starting at 1, instead of inferring them
+	Key []string
+	// Family is a pattern like "node_cpu_*" picking the metric families
+	// read from Prometheus input, all of them are read when empty
+	Family string
+	// Separator of the fields, like ',' or Whitespace, instead of sniffing
+	// it from the input
+	Separator rune
+	// Regexp with named groups like `(?P<pid>\d+) (?P<name>\S+)` picks the
+	// columns out of each line, overriding the format
+	Regexp string
+	// Select is a path like "data.items" or "$.data.items[*]" to the rows
+	// in JSON input, which is otherwise searched for them
+	Select string
+}
+
+// NewTable parses a table from fd, guessing the format from the input.
 func NewTable(fd io.Reader) ([]string, [][]string, error) {
+	return Parse(fd, Options{})
+}
+
+// Parse reads a table in the given format from fd, returning the header and
+// the rows of the table.
+func Parse(fd io.Reader, opts Options) ([]string, [][]string, error) {
+	header, rows, _, err := parse(fd, opts)
+	return header, rows, err
+}
+
+// parsed describes how parse read a table.
+type parsed struct {
+	// format and separator the table was read with, sniffed or given
+	format    Format
+	separator rune
+	// skipped is the number of lines that didn't match the regexp in the
+	// options
+	skipped int
+}
+
+// parse reads a table like Parse, also returning how it was read.
+func parse(fd io.Reader, opts Options) ([]string, [][]string, parsed, error) {
+
+	data, err := ioutil.ReadAll(fd)
+	if err != nil {
+		return nil, nil, parsed{}, err
+	}
+
+	// drop the byte order mark spreadsheets like to add to exports
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	// a regexp picks the columns out of each line whatever the format
+	if opts.Regexp != "" {
+		header, rows, skipped, err := readRegexp(data, opts)
+		return header, rows, parsed{skipped: skipped}, err
+	}
+
+	format := opts.Format
+	sep := opts.Separator
+	if format == "" || format == FormatAuto {
+		// a given separator means the input is delimited text
+		format = FormatText
+		if opts.Select != "" {
+			format = FormatJSON
+		} else if sep == 0 {
+			format, sep = SniffFormat(data, opts.Comment)
+		}
+	}
 
-	var table [][]string
 	var header []string
-	sep := ""
+	var rows [][]string
+	switch format {
+	case FormatCSV:
+		if sep == 0 || sep == Whitespace {
+			sep = ','
+		}
+		header, rows, err = readCSV(data, opts, sep)
+	case FormatTSV:
+		sep = '\t'
+		header, rows, err = readTSV(data, opts)
+	case FormatJSON:
+		header, rows, err = readJSON(data, opts.Select)
+	case FormatPrometheus:
+		header, rows, err = readPrometheus(data, opts.Family)
+	case FormatLogfmt:
+		header, rows, err = readLogfmt(data, opts)
+	case FormatBox:
+		header, rows, err = readBox(data)
+	case FormatText:
+		switch sep {
+		case 0, Whitespace:
+			sep = Whitespace
+			header, rows, err = readText(data)
+		case '\t':
+			header, rows, err = readTSV(data, opts)
+		default:
+			header, rows, err = readCSV(data, opts, sep)
+		}
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
 
-	scanner := bufio.NewScanner(fd)
+	return header, rows, parsed{format: format, separator: sep}, err
+}
+
+// SniffFormat guesses the format of the input, and the separator of its
+// fields if it's text.
+func SniffFormat(data []byte, comment rune) (Format, rune) {
+	switch {
+	case looksLikeJSON(data):
+		return FormatJSON, 0
+	case looksLikePrometheus(data):
+		return FormatPrometheus, 0
+	case looksLikeBox(data):
+		return FormatBox, 0
+	case looksLikeLogfmt(data):
+		return FormatLogfmt, 0
+	}
+	return FormatText, SniffSeparator(data, comment)
+}
+
+func readText(data []byte) ([]string, [][]string, error) {
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
 	for scanner.Scan() {
-		line := scanner.Text()
-
-		// Assume first line is header
-		if header == nil {
-			if strings.ContainsAny(line, ",") {
-				sep = ","
-			} else if strings.ContainsAny(line, "\t") {
-				sep = "\t"
-			} else if strings.ContainsAny(line, ":") {
-				sep = ":"
-			}
-
-			if sep != "" {
-				for _, v := range strings.Split(line, sep) {
-					header = append(header, strings.TrimSpace(v))
-				}
-			} else {
-				for _, v := range strings.Fields(line) {
-					header = append(header, strings.TrimSpace(v))
-				}
-			}
+		lines = append(lines, scanner.Text())
+	}
 
-			continue
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	// skip anything printed before or after the table, and the blank lines
+	// before it when there's no header to find
+	start, end := FindTable(lines)
+	lines = lines[start:end]
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if len(lines) == 0 {
+		return nil, nil, nil
+	}
+
+	// a summary with no table under it (e.g. a frame of top cut at its
+	// "MiB Swap:" line) has no header to read
+	if looksLikeProse(lines) {
+		return nil, nil, nil
+	}
+
+	var table [][]string
+	var header []string
+
+	// use the column offsets when the output is aligned
+	// (e.g. values with spaces in them like "Up 3 hours" from docker)
+	line := nameFirstColumn(lines[0], lines[1:])
+	if layout := newFixedLayout(line, lines[1:]); layout != nil && !isUniform(line, lines[1:]) {
+		return layout.header(), layout.rows(lines[1:]), nil
+	}
+
+	for _, v := range strings.Fields(line) {
+		header = append(header, strings.TrimSpace(v))
+	}
+
+	for _, line := range lines[1:] {
+		if row := fitRow(strings.Fields(line), len(header), " "); row != nil {
+			table = append(table, row)
 		}
+	}
 
-		var fields []string
-		if sep != "" {
-			fields = strings.Split(line, sep)
-		} else {
-			fields = strings.Fields(line)
+	return header, table, nil
+}
+
+// isUniform reports whether every line has one field per title in the header,
+// in which case splitting on whitespace is safer than the column offsets as
+// wide values can push the rest of a line out of line with the titles (e.g.
+// the memory columns of vmstat).
+func isUniform(header string, lines []string) bool {
+	n := len(strings.Fields(header))
+	for _, line := range lines {
+		if len(strings.Fields(line)) != n {
+			return false
 		}
+	}
+	return true
+}
 
-		if len(fields) > len(header) {
-			// concat trailing fields to last column
-			// (e.g. a process name with spaces in it)
-			cat1 := fields[len(header)-1:]
-			cat2 := strings.Join(cat1, " ")
-			catFields := append(fields[0:len(header)-1], cat2)
-			table = append(table, catFields)
-		} else if len(fields) == len(header) {
-			// normal line matches up with header
-			table = append(table, fields)
-		} else {
-			// incomplete line
+// nameFirstColumn adds a name for the first column when the header leaves it
+// out, like free does above its "Mem:" and "Swap:" rows.
+func nameFirstColumn(header string, lines []string) string {
+
+	const name = "name"
+	indent := len(header) - len(strings.TrimLeft(header, " "))
+	if indent <= len(name) {
+		return header
+	}
+
+	named, rows := 0, 0
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
 			continue
 		}
+		rows++
+		if i := strings.IndexAny(line, " \t"); line[0] != ' ' && i > 0 && i < indent {
+			named++
+		}
+	}
+	if named*2 <= rows {
+		return header
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, nil, err
+	return name + header[len(name):]
+}
+
+// fitRow lines fields up with a header of n columns, returning nil for an
+// incomplete row.
+func fitRow(fields []string, n int, sep string) []string {
+
+	if n < 1 {
+		return nil
 	}
 
-	return header, table, nil
+	if len(fields) > n {
+		// concat trailing fields to last column
+		// (e.g. a process name with spaces in it)
+		cat1 := fields[n-1:]
+		cat2 := strings.Join(cat1, sep)
+		return append(fields[0:n-1], cat2)
+	} else if len(fields) == n {
+		// normal line matches up with header
+		return fields
+	}
+
+	// incomplete line
+	return nil
 }
@@ -17,6 +17,8 @@ package text2table
 import (
 	"io"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -47,6 +49,49 @@ func TestNewTable(t *testing.T) {
 	}
 }
 
+func TestNewTableSplitFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		header []string
+		rows   [][]string
+	}{
+		{
+			name:   "blank line before a headerless table",
+			input:  "\n1 2\n3 4\n",
+			header: []string{"1", "2"},
+			rows:   [][]string{{"3", "4"}},
+		},
+		{
+			name:  "only blank lines",
+			input: "\n  \n\n",
+		},
+		{
+			name:   "single spaced titles over prose",
+			input:  "id name msg\n1 alice hello world\n2 bob hi there",
+			header: []string{"id", "name", "msg"},
+			rows:   [][]string{{"1", "alice", "hello world"}, {"2", "bob", "hi there"}},
+		},
+		{
+			name:   "values not lined up with titles",
+			input:  "PID CMD\n1 a b c\n2 d",
+			header: []string{"PID", "CMD"},
+			rows:   [][]string{{"1", "a b c"}, {"2", "d"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header, rows, err := NewTable(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("NewTable() error = %v", err)
+			}
+			if !reflect.DeepEqual(header, tt.header) || !reflect.DeepEqual(rows, tt.rows) {
+				t.Errorf("NewTable() = %q, %q, want %q, %q", header, rows, tt.header, tt.rows)
+			}
+		})
+	}
+}
+
 func Test_isTableHeader(t *testing.T) {
 	type args struct {
 		s string
@@ -61,6 +106,46 @@ func Test_isTableHeader(t *testing.T) {
 			args: args{s: "USER               PID  %CPU %MEM      VSZ    RSS   TT  STAT STARTED      TIME COMMAND"},
 			want: true,
 		},
+		{
+			name: "top header",
+			args: args{s: "    PID USER      PR  NI    VIRT    RES    SHR S  %CPU  %MEM     TIME+ COMMAND"},
+			want: true,
+		},
+		{
+			name: "vmstat header",
+			args: args{s: " r  b   swpd   free   buff  cache   si   so    bi    bo   in   cs us sy id wa st"},
+			want: true,
+		},
+		{
+			name: "free header",
+			args: args{s: "               total        used        free      shared  buff/cache   available"},
+			want: true,
+		},
+		{
+			name: "top summary",
+			args: args{s: "Tasks: 287 total,   1 running, 286 sleeping,   0 stopped,   0 zombie"},
+			want: false,
+		},
+		{
+			name: "ps row",
+			args: args{s: "root                 1   0.0  0.1  4367432  13076   ??  Ss   Thu07AM  10:22.51 /sbin/launchd"},
+			want: false,
+		},
+		{
+			name: "vmstat data",
+			args: args{s: " 1  0      0 1232124 402312 6321940    0    0    26    54  301  612  3  1 96  0  0"},
+			want: false,
+		},
+		{
+			name: "single word",
+			args: args{s: "Filesystem"},
+			want: false,
+		},
+		{
+			name: "blank",
+			args: args{s: ""},
+			want: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
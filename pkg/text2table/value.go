@@ -0,0 +1,352 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Unit is the base unit a Value is normalized to.
+type Unit string
+
+const (
+	// UnitNone is a plain number
+	UnitNone Unit = ""
+	// UnitBytes is a size in bytes
+	UnitBytes Unit = "B"
+	// UnitPercent is a percentage
+	UnitPercent Unit = "%"
+	// UnitSeconds is a duration in seconds
+	UnitSeconds Unit = "s"
+)
+
+// Value is a number parsed from a table cell, such as "1.5G" from df,
+// "12.3MiB / 2GiB" from docker stats or "1:23.45" from ps.
+type Value struct {
+	// Number normalized to the base unit (e.g. bytes for "1.5G")
+	Number float64
+	// Unit of the number
+	Unit Unit
+	// Total is the right hand side of an "a / b" ratio
+	Total float64
+	// Ratio is set when the value was written as "a / b"
+	Ratio bool
+}
+
+// sizes are the multipliers of the size suffixes, where a bare letter is
+// binary as in `df -h` and `ls -h` while a letter followed by B is decimal
+// as in `docker stats` and `df -H`.
+var sizes = map[string]float64{
+	"B": 1,
+	"K": 1 << 10, "M": 1 << 20, "G": 1 << 30, "T": 1 << 40, "P": 1 << 50, "E": 1 << 60,
+	"Ki": 1 << 10, "Mi": 1 << 20, "Gi": 1 << 30, "Ti": 1 << 40, "Pi": 1 << 50, "Ei": 1 << 60,
+	"KiB": 1 << 10, "MiB": 1 << 20, "GiB": 1 << 30, "TiB": 1 << 40, "PiB": 1 << 50, "EiB": 1 << 60,
+	"kB": 1e3, "KB": 1e3, "MB": 1e6, "GB": 1e9, "TB": 1e12, "PB": 1e15, "EB": 1e18,
+}
+
+// durations are the seconds in each compact duration suffix, a superset of
+// the ones time.ParseDuration accepts that adds days, weeks and years as
+// used by kubectl (e.g. "3d2h").
+var durations = []struct {
+	suffix  string
+	seconds float64
+}{
+	{"ns", 1e-9}, {"us", 1e-6}, {"µs", 1e-6}, {"μs", 1e-6}, {"ms", 1e-3},
+	{"s", 1}, {"m", 60}, {"h", 3600}, {"d", 86400}, {"w", 7 * 86400}, {"y", 365 * 86400},
+}
+
+// words are the seconds in each spelled out duration unit as used by
+// `uptime` and docker (e.g. "up 3 days" and "Up About a minute").
+var words = map[string]float64{
+	"sec": 1, "secs": 1, "second": 1, "seconds": 1,
+	"min": 60, "mins": 60, "minute": 60, "minutes": 60,
+	"hour": 3600, "hours": 3600,
+	"day": 86400, "days": 86400,
+	"week": 7 * 86400, "weeks": 7 * 86400,
+	"month": 30 * 86400, "months": 30 * 86400,
+	"year": 365 * 86400, "years": 365 * 86400,
+}
+
+// clock matches clock style durations, [[dd-]hh:]mm:ss[.xx] as used by ps.
+var clock = regexp.MustCompile(`^(?:(\d+)-)?(?:(\d+):)?(\d+):(\d+(?:\.\d+)?)$`)
+
+// ParseValue parses a number that may have a size suffix, percent sign, or be
+// a duration or an "a / b" ratio, normalizing it to its base unit.
+func ParseValue(s string) (Value, error) {
+
+	s = strings.TrimSpace(s)
+
+	// drop trailing notes like "1 (3d2h ago)" from kubectl or
+	// "Up 3 hours (healthy)" from docker
+	if i := strings.Index(s, " ("); i > 0 && strings.HasSuffix(s, ")") {
+		s = strings.TrimSpace(s[:i])
+	}
+
+	// ratios like "12.3MiB / 2GiB" or "1/1"
+	if i := strings.Index(s, "/"); i > 0 && strings.Count(s, "/") == 1 {
+		a, errA := parseQuantity(s[:i])
+		b, errB := parseQuantity(s[i+1:])
+		if errA == nil && errB == nil && a.Unit == b.Unit {
+			return Value{Number: a.Number, Unit: a.Unit, Total: b.Number, Ratio: true}, nil
+		}
+	}
+
+	return parseQuantity(s)
+}
+
+// parseQuantity parses a single number with an optional unit.
+func parseQuantity(s string) (Value, error) {
+
+	s = strings.TrimSpace(s)
+	if n, err := parseNumber(s); err == nil {
+		return Value{Number: n}, nil
+	}
+
+	if strings.HasSuffix(s, "%") {
+		if n, err := parseNumber(strings.TrimSpace(s[:len(s)-1])); err == nil {
+			return Value{Number: n, Unit: UnitPercent}, nil
+		}
+	}
+
+	num, suffix := splitNumber(s)
+	if num != "" {
+		n, err := strconv.ParseFloat(num, 64)
+		if err == nil {
+			if m, ok := sizes[strings.TrimSpace(suffix)]; ok {
+				return Value{Number: n * m, Unit: UnitBytes}, nil
+			}
+		}
+	}
+
+	if n, ok := parseClock(s); ok {
+		return Value{Number: n, Unit: UnitSeconds}, nil
+	}
+
+	if n, ok := parseDuration(s); ok {
+		return Value{Number: n, Unit: UnitSeconds}, nil
+	}
+
+	if n, ok := parseWords(s); ok {
+		return Value{Number: n, Unit: UnitSeconds}, nil
+	}
+
+	return Value{}, fmt.Errorf("not a numeric value: %q", s)
+}
+
+// parseNumber parses a finite number, as "NaN" or "Inf" in a table are
+// words rather than values.
+func parseNumber(s string) (float64, error) {
+	n, err := strconv.ParseFloat(s, 64)
+	if err == nil && (math.IsNaN(n) || math.IsInf(n, 0)) {
+		return 0, fmt.Errorf("not a finite number: %q", s)
+	}
+	return n, err
+}
+
+// parseMilli parses a number with just an "m" after it like "100m", which
+// is minutes in a duration like kubectl's "5m" age but thousandths in a
+// Kubernetes quantity like the "250m" cores of kubectl top. It's left to
+// the other values in the column to tell which.
+func parseMilli(s string) (float64, bool) {
+	num, suffix := splitNumber(strings.TrimSpace(s))
+	if num == "" || suffix != "m" {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	return n, err == nil
+}
+
+// parseClock parses [[dd-]hh:]mm:ss[.xx] into seconds.
+func parseClock(s string) (float64, bool) {
+	m := clock.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+	days, _ := strconv.ParseFloat("0"+m[1], 64)
+	hours, _ := strconv.ParseFloat("0"+m[2], 64)
+	mins, _ := strconv.ParseFloat(m[3], 64)
+	secs, _ := strconv.ParseFloat(m[4], 64)
+	return days*86400 + hours*3600 + mins*60 + secs, true
+}
+
+// parseDuration parses compact durations like "1h2m3s", "350ms" or "12d"
+// into seconds, but for a bare number of minutes like "5m" (see
+// parseMilli).
+func parseDuration(s string) (float64, bool) {
+	if _, ok := parseMilli(s); s == "" || ok {
+		return 0, false
+	}
+	total := 0.0
+	for s != "" {
+		num, rest := splitNumber(s)
+		if num == "" {
+			return 0, false
+		}
+		n, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			return 0, false
+		}
+
+		found := false
+		for _, d := range durations {
+			if strings.HasPrefix(rest, d.suffix) {
+				// "m" is minutes unless it's the start of "ms"
+				if d.suffix == "m" && strings.HasPrefix(rest, "ms") {
+					continue
+				}
+				total += n * d.seconds
+				s = rest[len(d.suffix):]
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, false
+		}
+	}
+	return total, true
+}
+
+// parseWords parses spelled out durations like "3 days, 4:05", "Up 3 hours"
+// or "About a minute ago" into seconds.
+func parseWords(s string) (float64, bool) {
+
+	s = strings.ToLower(s)
+	s = strings.Replace(s, ",", " ", -1)
+	tokens := strings.Fields(s)
+	if len(tokens) > 0 && tokens[0] == "up" {
+		tokens = tokens[1:]
+	}
+	if len(tokens) > 0 && tokens[len(tokens)-1] == "ago" {
+		tokens = tokens[:len(tokens)-1]
+	}
+
+	total := 0.0
+	units := 0
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch t {
+		case "about", "almost", "over", "less", "than":
+			continue
+		}
+
+		// hours and minutes after the days in uptime
+		if h, m, ok := hoursMinutes(t); ok && units > 0 {
+			total += h*3600 + m*60
+			units++
+			continue
+		}
+
+		var n float64
+		if t == "a" || t == "an" {
+			n = 1
+		} else if v, err := strconv.ParseFloat(t, 64); err == nil {
+			n = v
+		} else {
+			return 0, false
+		}
+
+		if i+1 >= len(tokens) {
+			return 0, false
+		}
+		i++
+		secs, ok := words[tokens[i]]
+		if !ok {
+			return 0, false
+		}
+		total += n * secs
+		units++
+	}
+
+	return total, units > 0
+}
+
+// hoursMinutes parses hh:mm.
+func hoursMinutes(s string) (float64, float64, bool) {
+	i := strings.Index(s, ":")
+	if i < 0 {
+		return 0, 0, false
+	}
+	h, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	m, err := strconv.ParseFloat(s[i+1:], 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return h, m, true
+}
+
+// splitNumber splits the leading decimal number off s.
+func splitNumber(s string) (string, string) {
+	i := 0
+	if i < len(s) && (s[i] == '-' || s[i] == '+') {
+		i++
+	}
+	digits := 0
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+		if s[i] != '.' {
+			digits++
+		}
+		i++
+	}
+	if digits == 0 {
+		return "", s
+	}
+	return s[:i], s[i:]
+}
+
+// String formats the value in its unit, e.g. "1.5 GiB", "4.5%" or "1m23.45s".
+func (v Value) String() string {
+	s := formatNumber(v.Number, v.Unit)
+	if v.Ratio {
+		s += " / " + formatNumber(v.Total, v.Unit)
+	}
+	return s
+}
+
+func formatNumber(n float64, u Unit) string {
+	switch u {
+	case UnitBytes:
+		suffixes := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+		i := 0
+		for math.Abs(n) >= 1024 && i < len(suffixes)-1 {
+			n /= 1024
+			i++
+		}
+		if i == 0 {
+			return strconv.FormatFloat(n, 'f', -1, 64) + " B"
+		}
+		return strconv.FormatFloat(n, 'f', 1, 64) + " " + suffixes[i]
+
+	case UnitPercent:
+		return strconv.FormatFloat(n, 'f', -1, 64) + "%"
+
+	case UnitSeconds:
+		d := time.Duration(n * float64(time.Second))
+		if d > time.Second {
+			d = d.Round(time.Millisecond)
+		}
+		return d.String()
+	}
+
+	return strconv.FormatFloat(n, 'g', -1, 64)
+}
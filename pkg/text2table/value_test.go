@@ -0,0 +1,87 @@
+// Copyright © 2018 Cove Schneider
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text2table
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Value
+	}{
+		{s: "42", want: Value{Number: 42}},
+		{s: "-0.5", want: Value{Number: -0.5}},
+		{s: "4.5%", want: Value{Number: 4.5, Unit: UnitPercent}},
+		{s: "1.5G", want: Value{Number: 1.5 * (1 << 30), Unit: UnitBytes}},
+		{s: "84K", want: Value{Number: 84 * 1024, Unit: UnitBytes}},
+		{s: "120Mi", want: Value{Number: 120 * (1 << 20), Unit: UnitBytes}},
+		{s: "1.05kB", want: Value{Number: 1050, Unit: UnitBytes}},
+		{s: "0B", want: Value{Number: 0, Unit: UnitBytes}},
+		{s: "12.5MiB / 2GiB", want: Value{Number: 12.5 * (1 << 20), Unit: UnitBytes, Total: 2 * (1 << 30), Ratio: true}},
+		{s: "1/1", want: Value{Number: 1, Total: 1, Ratio: true}},
+		{s: "1:23.5", want: Value{Number: 83.5, Unit: UnitSeconds}},
+		{s: "01:02:03", want: Value{Number: 3723, Unit: UnitSeconds}},
+		{s: "2-03:04:05", want: Value{Number: 2*86400 + 3*3600 + 4*60 + 5, Unit: UnitSeconds}},
+		{s: "1h2m3s", want: Value{Number: 3723, Unit: UnitSeconds}},
+		{s: "250ms", want: Value{Number: 0.25, Unit: UnitSeconds}},
+		{s: "3d2h", want: Value{Number: 3*86400 + 2*3600, Unit: UnitSeconds}},
+		{s: "up 3 days, 4:05", want: Value{Number: 3*86400 + 4*3600 + 5*60, Unit: UnitSeconds}},
+		{s: "Up About a minute", want: Value{Number: 60, Unit: UnitSeconds}},
+		{s: "Up 3 hours (healthy)", want: Value{Number: 3 * 3600, Unit: UnitSeconds}},
+		{s: "2 hours ago", want: Value{Number: 2 * 3600, Unit: UnitSeconds}},
+		{s: "214 (4m ago)", want: Value{Number: 214}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseValue(tt.s)
+			if err != nil {
+				t.Fatalf("ParseValue() error = %v", err)
+			}
+			if math.Abs(got.Number-tt.want.Number) > 1e-9 || got.Unit != tt.want.Unit ||
+				got.Total != tt.want.Total || got.Ratio != tt.want.Ratio {
+				t.Errorf("ParseValue() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseValueNotNumeric(t *testing.T) {
+	for _, s := range []string{"", "root", "/dev/sda1", "10:56AM", "Ss+", "1 user", "10/17/2018", "NaN", "Inf", "-infinity", "+Inf%", "100m"} {
+		if v, err := ParseValue(s); err == nil {
+			t.Errorf("ParseValue(%q) = %+v, want error", s, v)
+		}
+	}
+}
+
+func TestValueString(t *testing.T) {
+	tests := []struct {
+		v    Value
+		want string
+	}{
+		{v: Value{Number: 1.5 * (1 << 30), Unit: UnitBytes}, want: "1.5 GiB"},
+		{v: Value{Number: 512, Unit: UnitBytes}, want: "512 B"},
+		{v: Value{Number: 4.5, Unit: UnitPercent}, want: "4.5%"},
+		{v: Value{Number: 83.5, Unit: UnitSeconds}, want: "1m23.5s"},
+		{v: Value{Number: 1, Total: 2, Ratio: true}, want: "1 / 2"},
+	}
+	for _, tt := range tests {
+		if got := tt.v.String(); got != tt.want {
+			t.Errorf("Value.String() = %q, want %q", got, tt.want)
+		}
+	}
+}